@@ -39,7 +39,7 @@ func main() {
 	// replace position 2 with the value 2
 	c.Store(12, 1)
 	c.Store(2, 2)
-	err := c.Run()
+	_, err := c.Run()
 	if err != nil {
 		panic(err)
 	}
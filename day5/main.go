@@ -54,7 +54,7 @@ func part1() {
 	logger := intcomputer.CreateLogger()
 	c := intcomputer.CreateIntComputer(input, logger, readInput, printOutput)
 
-	err := c.Run() // ans: 9961446
+	_, err := c.Run() // ans: 9961446
 	if err != nil {
 		panic(err)
 	}
@@ -69,7 +69,7 @@ func part2() {
 	logger := intcomputer.CreateLogger()
 	c := intcomputer.CreateIntComputer(input, logger, readInput, printOutput)
 
-	err := c.Run()
+	_, err := c.Run()
 	if err != nil {
 		panic(err)
 	}
@@ -1,82 +1,157 @@
 package amplifier
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
 )
 
 const (
 	ampStateInit              = -1
-	ampStatePhaseProvided     = 0
-	ampStateInputProvided     = 1
+	ampStateAwaitingInput     = 0
+	ampStateHalted            = 1
 	ampStateFinishedExecution = 2
 )
 
 type Amplifier struct {
 	c                    *intcomputer.IntComputer
+	logger               intcomputer.Logger
 	state                int
 	phase, input, output int
-	ampProgram           []int
 	isFeedbackMode       bool
+	limits               intcomputer.Limits
+	// last is the result of the latest run
+	last intcomputer.RunResult
 }
 
 func CreateAmp(instructions []int,
-	logger *intcomputer.Logger, phase, input int,
+	logger intcomputer.Logger, phase, input int,
 	feedbackMode bool) *Amplifier {
-	prog := make([]int, len(instructions))
-	copy(prog, instructions)
 	a := &Amplifier{
 		c:              intcomputer.CreateIntComputer(instructions, logger, nil, nil),
+		logger:         logger,
 		state:          ampStateInit,
 		phase:          phase,
 		input:          input,
-		ampProgram:     prog,
 		isFeedbackMode: feedbackMode,
 	}
-	a.c.InFunc = func() int {
-		ret := -1
-		switch a.state {
-		case ampStateInit:
-			a.state = ampStatePhaseProvided
-			ret = a.phase
-		case ampStateInputProvided, ampStatePhaseProvided:
-			a.state = ampStateInputProvided
-			ret = a.input
-		}
-		return ret
-	}
 	a.c.OutFunc = func(x int) {
 		a.output = x
-		if feedbackMode {
-			a.c.Break()
+		if a.logger != nil {
+			a.logger.Log(intcomputer.LevelDebug, "[Amplifier] Output",
+				"phase", a.phase, "output", x)
 		}
 	}
+	a.c.QueueInput(phase)
 	return a
 }
 
+// SetLimits bounds every run of the amplifier
+func (a *Amplifier) SetLimits(l intcomputer.Limits) {
+	a.limits = l
+	a.c.Limits = l
+}
+
+// SetTracer hands every instruction the amplifier executes to t
+func (a *Amplifier) SetTracer(t intcomputer.Tracer) {
+	a.c.Tracer = t
+}
+
 func (a *Amplifier) Run(in int) error {
+	return a.run(in, intcomputer.Limits{})
+}
+
+// run is Run with extra limits imposed by the circuit
+func (a *Amplifier) run(in int, extra intcomputer.Limits) error {
 	a.input = in
-	if a.c.IsHalted() {
+	a.last = intcomputer.RunResult{}
+	if a.state == ampStateHalted {
 		a.state = ampStateFinishedExecution
 		return nil
 	}
-	a.c.Resume()
-	return a.c.Run()
+	// runs until halted or, in feedback mode, starved for the next input
+	a.c.Limits = a.limits.Min(extra)
+	a.c.QueueInput(in)
+	res, err := a.c.Resume()
+	a.last = res
+	if err != nil {
+		return err
+	}
+	switch res.Reason {
+	case intcomputer.StopHalted:
+		a.state = ampStateHalted
+	case intcomputer.StopAwaitingInput:
+		a.state = ampStateAwaitingInput
+	default:
+		return fmt.Errorf("Amplifier stopped unexpectedly %s", res)
+	}
+	return nil
 }
 
 func (a *Amplifier) Reset() {
 	a.state = ampStateInit
 	a.c.Reset()
-	a.c.Program(a.ampProgram)
+	a.c.QueueInput(a.phase)
 }
 
 type SeriesAmpCircuit struct {
-	n  int
-	as []*Amplifier
+	n      int
+	as     []*Amplifier
+	limits intcomputer.Limits
+}
+
+// SetLimits bounds a whole run of the circuit. Run counts instructions and
+// outputs over all amplifiers together, RunConcurrent counts them per
+// amplifier, so a circuit of n amplifiers may execute n*MaxSteps
+// instructions there. The timeout always covers the whole circuit.
+func (ac *SeriesAmpCircuit) SetLimits(l intcomputer.Limits) {
+	ac.limits = l
+}
+
+// SetTracer hands every instruction of every amplifier to t, which must
+// be safe for concurrent use for RunConcurrent
+func (ac *SeriesAmpCircuit) SetTracer(t intcomputer.Tracer) {
+	for _, a := range ac.as {
+		a.SetTracer(t)
+	}
+}
+
+// SetEngine selects how the amplifiers execute their program
+func (ac *SeriesAmpCircuit) SetEngine(e intcomputer.Engine) {
+	for _, a := range ac.as {
+		a.c.Engine = e
+	}
+}
+
+// remaining returns what is left of the circuit limits after steps
+// instructions and outs outputs, or the limit that has run out
+func (ac *SeriesAmpCircuit) remaining(steps, outs int,
+	start time.Time) (intcomputer.Limits, error) {
+	l := ac.limits
+	if l.MaxSteps > 0 {
+		if l.MaxSteps -= steps; l.MaxSteps <= 0 {
+			return l, &intcomputer.ErrLimitExceeded{Limit: intcomputer.LimitSteps}
+		}
+	}
+	if l.MaxOutputs > 0 {
+		if l.MaxOutputs -= outs; l.MaxOutputs <= 0 {
+			return l, &intcomputer.ErrLimitExceeded{Limit: intcomputer.LimitOutputs}
+		}
+	}
+	if l.Timeout > 0 {
+		if l.Timeout -= time.Since(start); l.Timeout <= 0 {
+			return l, &intcomputer.ErrLimitExceeded{Limit: intcomputer.LimitTime}
+		}
+	}
+	return l, nil
 }
 
 func CreateAmpCircuit(
 	n int, pSettings []int,
-	instructions []int, logger *intcomputer.Logger,
+	instructions []int, logger intcomputer.Logger,
 	feedbackMode bool) *SeriesAmpCircuit {
 	as := make([]*Amplifier, n)
 
@@ -93,11 +168,19 @@ func CreateAmpCircuit(
 
 func (ac *SeriesAmpCircuit) Run(circuitIn int, feedbackMode bool) (int, error) {
 	i := 0
+	start := time.Now()
+	steps, outs := 0, 0
 	for {
 		amp, nxtAmp := ac.as[i%ac.n], ac.as[(i+1)%ac.n]
-		if err := amp.Run(amp.input); err != nil {
+		l, err := ac.remaining(steps, outs, start)
+		if err != nil {
 			return amp.output, err
 		}
+		if err := amp.run(amp.input, l); err != nil {
+			return amp.output, err
+		}
+		steps += amp.last.Steps
+		outs += amp.last.Outputs
 		if amp.state == ampStateFinishedExecution {
 			break
 		}
@@ -108,3 +191,106 @@ func (ac *SeriesAmpCircuit) Run(circuitIn int, feedbackMode bool) (int, error) {
 
 	return ac.as[ac.n-1].output, nil
 }
+
+// RunConcurrent runs every amplifier of the circuit in its own goroutine,
+// wired together with channels. In feedback mode the last amplifier feeds
+// the first one until they all halt. The step and output limits of the
+// circuit apply to each amplifier on its own.
+func (ac *SeriesAmpCircuit) RunConcurrent(ctx context.Context,
+	circuitIn int, feedbackMode bool) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// ins[i] feeds amplifier i, out collects the circuit output. Phase
+	// settings are already queued on the amplifiers.
+	ins := make([]chan int, ac.n)
+	for i := range ins {
+		ins[i] = make(chan int, 2)
+	}
+	out := make(chan int, 2)
+
+	dones := make([]<-chan intcomputer.RunResult, ac.n)
+	for i, amp := range ac.as {
+		amp.Reset()
+		amp.c.Limits = amp.limits.Min(ac.limits)
+		next := out
+		if i+1 < ac.n {
+			next = ins[i+1]
+		}
+		dones[i] = amp.c.Start(ctx, ins[i], next)
+	}
+	ins[0] <- circuitIn
+
+	// keeps the last output of the circuit, feeding it back in feedback
+	// mode, until the amplifiers are stopped
+	last := 0
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for {
+			select {
+			case v := <-out:
+				last = v
+				if !feedbackMode {
+					continue
+				}
+				select {
+				case ins[0] <- v:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// amplifiers finish in any order. The first failure stops the others,
+	// which would otherwise wait for input forever, and so does the last
+	// amplifier halting, as nothing reads what the others still write. An
+	// amplifier halting closes the input of the next one, which stops once
+	// it has read what is left.
+	type ampResult struct {
+		i   int
+		res intcomputer.RunResult
+	}
+	results := make(chan ampResult, ac.n)
+	for i, done := range dones {
+		go func(i int, done <-chan intcomputer.RunResult) {
+			results <- ampResult{i, <-done}
+		}(i, done)
+	}
+	var err error
+	halted := false
+	for range dones {
+		r := <-results
+		stopped := r.res.Err == nil || errors.Is(r.res.Err, intcomputer.ErrInputClosed)
+		switch {
+		case !stopped:
+			if err == nil && !halted {
+				err = r.res.Err
+				cancel()
+			}
+		case r.i == ac.n-1:
+			halted = true
+			cancel()
+		default:
+			close(ins[r.i+1])
+		}
+	}
+	cancel()
+	<-collected
+	for {
+		select {
+		case v := <-out:
+			last = v
+			continue
+		default:
+		}
+		break
+	}
+	if err != nil {
+		return 0, err
+	}
+	return last, nil
+}
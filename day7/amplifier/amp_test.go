@@ -1,7 +1,10 @@
 package amplifier
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
 )
@@ -94,3 +97,175 @@ func TestCircuitFeedback(t *testing.T) {
 		t.Logf(" -------- [TestCircuitFeedback] o/p: %d --------- ", ret)
 	}
 }
+
+func TestCircuitConcurrent(t *testing.T) {
+	tt := []struct {
+		instructions []int
+		ps           []int
+		feedbackMode bool
+		expected     int
+	}{
+		{
+			instructions: []int{3, 15, 3, 16, 1002, 16, 10, 16, 1, 16, 15,
+				15, 4, 15, 99, 0, 0},
+			ps:       []int{4, 3, 2, 1, 0},
+			expected: 43210,
+		},
+		{
+			instructions: []int{3, 26, 1001, 26, -4, 26, 3, 27, 1002, 27, 2,
+				27, 1, 27, 26, 27, 4, 27, 1001, 28, -1, 28, 1005, 28, 6, 99, 0,
+				0, 5},
+			ps:           []int{9, 8, 7, 6, 5},
+			feedbackMode: true,
+			expected:     139629729,
+		},
+	}
+
+	n := 5
+	for _, tc := range tt {
+		log := intcomputer.CreateLogger()
+		c := CreateAmpCircuit(n, tc.ps, tc.instructions, log, tc.feedbackMode)
+
+		// amplifiers are reset between runs
+		for run := 0; run < 2; run++ {
+			ret, err := c.RunConcurrent(context.Background(), 0, tc.feedbackMode)
+			if err != nil {
+				t.Errorf("ERROR: %s", err)
+				t.FailNow()
+			}
+			if ret != tc.expected {
+				t.Errorf("Circuit out: %d expected %d", ret, tc.expected)
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestCircuitConcurrentFault(t *testing.T) {
+	instructions := []int{3, 26, 1001, 26, -4, 26, 3, 27, 1002, 27, 2,
+		27, 1, 27, 26, 27, 4, 27, 1001, 28, -1, 28, 1005, 28, 6, 99, 0,
+		0, 5}
+	// reads its phase and an input, then hits an unknown opcode
+	faulty := []int{3, 6, 3, 6, 42, 99, 0}
+
+	c := CreateAmpCircuit(5, []int{9, 8, 7, 6, 5}, instructions, nil, true)
+	c.as[2] = CreateAmp(faulty, nil, 7, 0, true)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := c.RunConcurrent(context.Background(), 0, true)
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		var ue *intcomputer.ErrUnsupportedOpcode
+		if !errors.As(err, &ue) {
+			t.Errorf("error %v expected unsupported opcode", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("circuit hangs after a fault")
+	}
+}
+
+func TestCircuitConcurrentNoOutput(t *testing.T) {
+	// reads its phase and an input, then halts without output
+	silent := []int{3, 5, 3, 5, 99, 0}
+	// outputs its input three times
+	chatty := []int{3, 11, 3, 11, 4, 11, 4, 11, 4, 11, 99, 0}
+	// the circuit input is the expected output
+	for _, tc := range []struct {
+		name         string
+		instructions []int
+		feedbackMode bool
+		expected     int
+	}{
+		{"silent", silent, false, 0},
+		{"silent feedback", silent, true, 0},
+		{"chatty", chatty, false, 5},
+	} {
+		c := CreateAmpCircuit(2, []int{0, 1}, tc.instructions, nil, tc.feedbackMode)
+		errs := make(chan error, 1)
+		var ret int
+		go func() {
+			var err error
+			ret, err = c.RunConcurrent(context.Background(), tc.expected, tc.feedbackMode)
+			errs <- err
+		}()
+		select {
+		case err := <-errs:
+			if err != nil || ret != tc.expected {
+				t.Errorf("%s: %d %v expected %d", tc.name, ret, err, tc.expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: circuit hangs", tc.name)
+		}
+	}
+}
+
+func TestCircuitLimits(t *testing.T) {
+	// takes an input, then adds it up forever without output
+	spin := []int{3, 9, 1, 9, 9, 9, 1105, 1, 2, 0}
+
+	c := CreateAmpCircuit(2, []int{1, 2}, spin, nil, false)
+	c.SetLimits(intcomputer.Limits{MaxSteps: 1000})
+	_, err := c.Run(0, false)
+	var le *intcomputer.ErrLimitExceeded
+	if !errors.As(err, &le) || le.Limit != intcomputer.LimitSteps {
+		t.Fatalf("error %v expected step limit", err)
+	}
+	if steps := c.as[0].last.Steps; steps != 1000 {
+		t.Errorf("first amplifier ran %d steps", steps)
+	}
+
+	c = CreateAmpCircuit(2, []int{1, 2}, spin, nil, false)
+	c.as[0].SetLimits(intcomputer.Limits{Timeout: 10 * time.Millisecond})
+	c.SetLimits(intcomputer.Limits{Timeout: time.Minute})
+	_, err = c.Run(0, false)
+	if !errors.As(err, &le) || le.Limit != intcomputer.LimitTime {
+		t.Fatalf("error %v expected timeout", err)
+	}
+
+	c.SetLimits(intcomputer.Limits{Timeout: 10 * time.Millisecond})
+	if _, err = c.RunConcurrent(context.Background(), 0, false); !errors.As(err, &le) {
+		t.Fatalf("error %v expected limit exceeded", err)
+	}
+}
+
+func TestCircuitCoverage(t *testing.T) {
+	instructions := []int{3, 15, 3, 16, 1002, 16, 10, 16, 1, 16, 15,
+		15, 4, 15, 99, 0, 0}
+
+	cv := intcomputer.NewCoverage()
+	for _, ps := range [][]int{{4, 3, 2, 1, 0}, {0, 1, 2, 3, 4}} {
+		c := CreateAmpCircuit(5, ps, instructions, nil, false)
+		c.SetTracer(cv)
+		if _, err := c.Run(0, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 2 circuits of 5 amplifiers
+	if n := cv.Addrs[0]; n != 10 {
+		t.Errorf("address 0 executed %d times expected 10", n)
+	}
+	s := cv.Stats(instructions)
+	if s.Covered != s.Instructions {
+		t.Errorf("stats: %s", s)
+	}
+}
+
+func BenchmarkCircuitFeedback(b *testing.B) {
+	instructions := []int{3, 26, 1001, 26, -4, 26, 3, 27, 1002, 27, 2,
+		27, 1, 27, 26, 27, 4, 27, 1001, 28, -1, 28, 1005, 28, 6, 99, 0,
+		0, 5}
+	c := CreateAmpCircuit(5, []int{9, 8, 7, 6, 5}, instructions, nil, true)
+	c.SetEngine(intcomputer.EngineCompiled)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, a := range c.as {
+			a.Reset()
+		}
+		c.as[0].input = 0
+		if ret, err := c.Run(0, true); err != nil || ret != 139629729 {
+			b.Fatalf("circuit out: %d %v", ret, err)
+		}
+	}
+}
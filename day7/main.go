@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
 	"os"
 	"strconv"
@@ -75,6 +77,12 @@ func maxOf(x, y int) int {
 	return y
 }
 
+// coverage, if set, collects the coverage of every circuit run
+var coverage *intcomputer.Coverage
+
+// engine runs the amplifiers
+var engine = intcomputer.EngineInterpreter
+
 func run(nAmps, pHigh, pLow int, feedback bool, inputFile string) int {
 	instructions, inputs := readInput(inputFile), generateInputs(nAmps, pLow, pHigh)
 	maxBoost := 0
@@ -95,6 +103,10 @@ func run(nAmps, pHigh, pLow int, feedback bool, inputFile string) int {
 
 		logger := intcomputer.CreateLogger()
 		c := amplifier.CreateAmpCircuit(nAmps, ps, instructions, logger, feedback)
+		c.SetEngine(engine)
+		if coverage != nil {
+			c.SetTracer(coverage)
+		}
 		ret, err := c.Run(0, false)
 		if err != nil {
 			panic(err)
@@ -119,7 +131,38 @@ func part2() {
 	fmt.Printf("[Part-2] Max Boost: %d\n", maxBoost)
 }
 
+func writeCoverage(path, inputFile string) {
+	f, err := os.Create(path)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := coverage.WriteListing(w, readInput(inputFile)); err != nil {
+		panic(err)
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
+
 func main() {
+	coverPath := flag.String("cover", "",
+		"write a listing of the code covered by all phase settings to this file")
+	compiled := flag.Bool("compiled", false,
+		"run the amplifiers with the experimental compiled engine")
+	flag.Parse()
+	if *compiled {
+		engine = intcomputer.EngineCompiled
+	}
+	if *coverPath != "" {
+		coverage = intcomputer.NewCoverage()
+	}
+
 	part1()
 	part2()
+
+	if *coverPath != "" {
+		writeCoverage(*coverPath, "day7-part1-input.txt")
+	}
 }
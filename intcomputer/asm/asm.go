@@ -0,0 +1,280 @@
+// Package asm assembles Intcode programs from text.
+//
+// Every line holds an optional label, an instruction or directive and an
+// optional comment:
+//
+//	; read a number and output its double
+//	.const N = 2
+//	start:  Input value
+//	        Mul value, #N, value
+//	        Output value
+//	        JumpIfTrue #1, #start
+//	value:  .data 0
+//
+// Mnemonics are the opcode names of the intcomputer package, matched
+// ignoring case. Operands are Position by default, #operand is Immediate
+// and @operand is Relative. An operand is an integer, a label or a
+// constant, or a sum of those such as value+1. A line may start with an
+// address such as 0012: as printed by the disassembler, which must match
+// the address the line is assembled at.
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// word is an expression to evaluate once all labels are known
+type word struct {
+	line int
+	expr string
+}
+
+// item is an instruction, or data words when modes is nil
+type item struct {
+	op    int
+	modes []int
+	words []word
+}
+
+type assembler struct {
+	items  []*item
+	addr   int
+	labels map[string]int
+	consts map[string]word
+	// constants being evaluated, to catch cycles
+	resolving map[string]bool
+}
+
+// Assemble turns the source text of a program into its words
+func Assemble(src string) ([]int, error) {
+	a := &assembler{
+		labels:    map[string]int{},
+		consts:    map[string]word{},
+		resolving: map[string]bool{},
+	}
+	for i, l := range strings.Split(src, "\n") {
+		if err := a.parseLine(i+1, l); err != nil {
+			return nil, err
+		}
+	}
+
+	ret := make([]int, 0, a.addr)
+	for _, it := range a.items {
+		vs := make([]int, len(it.words))
+		for i, w := range it.words {
+			v, err := a.eval(w)
+			if err != nil {
+				return nil, err
+			}
+			vs[i] = v
+		}
+		if it.modes == nil {
+			ret = append(ret, vs...)
+			continue
+		}
+		code, scale := it.op, 100
+		for _, m := range it.modes {
+			code += m * scale
+			scale *= 10
+		}
+		ret = append(ret, code)
+		ret = append(ret, vs...)
+	}
+	return ret, nil
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func (a *assembler) parseLine(n int, l string) error {
+	if i := strings.IndexByte(l, ';'); i >= 0 {
+		l = l[:i]
+	}
+	l = strings.TrimSpace(l)
+
+	// leading address and labels
+	for {
+		i := strings.IndexByte(l, ':')
+		if i < 0 {
+			break
+		}
+		name := strings.TrimSpace(l[:i])
+		switch {
+		case isNumber(name):
+			addr, _ := strconv.Atoi(name)
+			if addr != a.addr {
+				return &Error{Line: n, Msg: fmt.Sprintf(
+					"address %d does not match assembled address %d", addr, a.addr)}
+			}
+		case isIdent(name):
+			if _, ok := a.labels[name]; ok {
+				return &Error{Line: n, Msg: fmt.Sprintf("label %q redefined", name)}
+			}
+			if _, ok := a.consts[name]; ok {
+				return &Error{Line: n, Msg: fmt.Sprintf("label %q is a constant", name)}
+			}
+			a.labels[name] = a.addr
+		default:
+			return &Error{Line: n, Msg: fmt.Sprintf("invalid label %q", name)}
+		}
+		l = strings.TrimSpace(l[i+1:])
+	}
+	if l == "" {
+		return nil
+	}
+
+	mnemonic, rest := l, ""
+	if i := strings.IndexAny(l, " \t"); i >= 0 {
+		mnemonic, rest = l[:i], strings.TrimSpace(l[i+1:])
+	}
+	args := []string{}
+	if rest != "" {
+		for _, arg := range strings.Split(rest, ",") {
+			args = append(args, strings.TrimSpace(arg))
+		}
+	}
+
+	switch strings.ToLower(mnemonic) {
+	case ".data":
+		it := &item{}
+		for _, arg := range args {
+			if arg == "" {
+				return &Error{Line: n, Msg: "empty data word"}
+			}
+			it.words = append(it.words, word{line: n, expr: arg})
+		}
+		a.items = append(a.items, it)
+		a.addr += len(it.words)
+		return nil
+	case ".const":
+		kv := strings.SplitN(rest, "=", 2)
+		name := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || !isIdent(name) {
+			return &Error{Line: n, Msg: "expected .const NAME = value"}
+		}
+		if _, ok := a.labels[name]; ok {
+			return &Error{Line: n, Msg: fmt.Sprintf("constant %q is a label", name)}
+		}
+		if _, ok := a.consts[name]; ok {
+			return &Error{Line: n, Msg: fmt.Sprintf("constant %q redefined", name)}
+		}
+		a.consts[name] = word{line: n, expr: strings.TrimSpace(kv[1])}
+		return nil
+	}
+
+	op, ok := intcomputer.LookupOpcode(mnemonic)
+	if !ok {
+		return &Error{Line: n, Msg: fmt.Sprintf("unknown mnemonic %q", mnemonic)}
+	}
+	if cnt := intcomputer.ParamCount(op); cnt != len(args) {
+		return &Error{Line: n, Msg: fmt.Sprintf("%s takes %d operands, got %d",
+			intcomputer.OpcodeName(op), cnt, len(args))}
+	}
+	it := &item{op: op, modes: make([]int, len(args))}
+	for i, arg := range args {
+		mode := intcomputer.Position
+		switch {
+		case strings.HasPrefix(arg, "#"):
+			mode, arg = intcomputer.Immediate, arg[1:]
+		case strings.HasPrefix(arg, "@"):
+			mode, arg = intcomputer.Relative, arg[1:]
+		}
+		if arg == "" {
+			return &Error{Line: n, Msg: fmt.Sprintf("empty operand %d", i+1)}
+		}
+		it.modes[i] = mode
+		it.words = append(it.words, word{line: n, expr: strings.TrimSpace(arg)})
+	}
+	a.items = append(a.items, it)
+	a.addr += 1 + len(args)
+	return nil
+}
+
+// eval computes a sum of terms, each an integer, label or constant
+func (a *assembler) eval(w word) (int, error) {
+	expr := strings.Join(strings.Fields(w.expr), "")
+	if expr == "" {
+		return 0, &Error{Line: w.line, Msg: "empty expression"}
+	}
+
+	ret, sign, start := 0, 1, 0
+	for i := 0; i <= len(expr); i++ {
+		if i < len(expr) && (expr[i] != '+' && expr[i] != '-' || i == start) {
+			continue
+		}
+		term := expr[start:i]
+		v, err := a.term(w.line, term)
+		if err != nil {
+			return 0, err
+		}
+		ret += sign * v
+		if i < len(expr) {
+			sign = 1
+			if expr[i] == '-' {
+				sign = -1
+			}
+		}
+		start = i + 1
+	}
+	return ret, nil
+}
+
+func (a *assembler) term(line int, t string) (int, error) {
+	if v, err := strconv.Atoi(t); err == nil {
+		return v, nil
+	}
+	neg := strings.HasPrefix(t, "-")
+	name := strings.TrimPrefix(t, "-")
+
+	v, ok := a.labels[name]
+	if !ok {
+		c, isConst := a.consts[name]
+		if !isConst {
+			return 0, &Error{Line: line, Msg: fmt.Sprintf("undefined symbol %q", t)}
+		}
+		if a.resolving[name] {
+			return 0, &Error{Line: line, Msg: fmt.Sprintf("constant %q depends on itself", name)}
+		}
+		a.resolving[name] = true
+		var err error
+		v, err = a.eval(c)
+		delete(a.resolving, name)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
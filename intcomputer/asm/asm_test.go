@@ -0,0 +1,155 @@
+package asm
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAssemble(t *testing.T) {
+	tt := []struct {
+		src      string
+		expected []int
+	}{
+		{
+			src: `
+			; read a number and output its double
+			.const N = 2
+			start:  Input value
+			        Mul value, #N, value
+			        Output value
+			        JumpIfTrue #1, #start
+			value:  .data 0`,
+			expected: []int{3, 11, 1002, 11, 2, 11, 4, 11, 1105, 1, 0, 0},
+		},
+		{
+			src: `
+			AdjustRelBase #buf+1   ; relative base at buf+1
+			input @-1
+			output @-1
+			halt
+			buf: .data 7, 8
+			.const LEN = end - buf
+			end:`,
+			expected: []int{109, 8, 203, -1, 204, -1, 99, 7, 8},
+		},
+		{
+			src:      "0000: Equals #1, @2, 3\n0004: .data -1, LEN\n.const LEN = 6 - 4",
+			expected: []int{2108, 1, 2, 3, -1, 2},
+		},
+	}
+
+	for i, tc := range tt {
+		p, err := Assemble(tc.src)
+		if err != nil {
+			t.Errorf("case %d: %s", i, err)
+			continue
+		}
+		if !equal(p, tc.expected) {
+			t.Errorf("case %d: assembled %v expected %v", i, p, tc.expected)
+		}
+	}
+}
+
+func TestAssembleRun(t *testing.T) {
+	p, err := Assemble(`
+		start:  Input value
+		        Mul value, #2, value
+		        Output value
+		        JumpIfTrue value, #start
+		        Halt
+		value:  .data 0`)
+	if err != nil {
+		t.Fatalf("Assemble: %s", err)
+	}
+
+	out := []int{}
+	c := intcomputer.CreateIntComputer(p, intcomputer.CreateLogger(), nil,
+		func(n int) {
+			out = append(out, n)
+		})
+	c.QueueInput(3, 4, 0)
+	if _, err := c.Run(); err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	if !equal(out, []int{6, 8, 0}) {
+		t.Errorf("output: %v expected [6 8 0]", out)
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tt := []struct {
+		src, msg string
+	}{
+		{src: "Frobnicate 1", msg: "line 1: unknown mnemonic"},
+		{src: "Halt\nAdd 1, 2", msg: "line 2: Add takes 3 operands"},
+		{src: "Output x", msg: "line 1: undefined symbol"},
+		{src: "a: Halt\na: Halt", msg: "line 2: label \"a\" redefined"},
+		{src: ".const A = B\n.const B = A\nOutput A", msg: "depends on itself"},
+		{src: "Halt\n0000: Halt", msg: "line 2: address 0 does not match"},
+		{src: "Output #", msg: "line 1: empty operand"},
+	}
+
+	for _, tc := range tt {
+		_, err := Assemble(tc.src)
+		if err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%q: error %v expected %q", tc.src, err, tc.msg)
+		}
+	}
+}
+
+func TestDisassemblyRoundTrip(t *testing.T) {
+	programs := [][]int{
+		{3, 21, 1008, 21, 8, 20, 1005, 20, 22, 107, 8, 21, 20, 1006, 20, 31,
+			1106, 0, 36, 98, 0, 0, 1002, 21, 125, 20, 4, 20, 1105, 1, 46, 104,
+			999, 1105, 1, 46, 1101, 1000, 1, 20, 4, 20, 1105, 1, 46, 98, 99},
+		{109, 1, 204, -1, 1001, 100, 1, 100, 1008, 100, 16, 101, 1006, 101,
+			0, 99},
+		{10101, 1, 2, 3, 99, 1099, -5, 3},
+	}
+	for _, f := range []string{
+		"../../day5/day5-part2-input.txt",
+		"../../day7/day7-part1-input.txt",
+	} {
+		r, err := os.Open(f)
+		if err != nil {
+			t.Logf("skipping %s: %s", f, err)
+			continue
+		}
+		p, err := intcomputer.ParseProgram(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", f, err)
+		}
+		programs = append(programs, p)
+	}
+
+	for i, p := range programs {
+		sb := &strings.Builder{}
+		if err := intcomputer.WriteListing(sb, p); err != nil {
+			t.Fatalf("WriteListing: %s", err)
+		}
+		q, err := Assemble(sb.String())
+		if err != nil {
+			t.Errorf("program %d: %s\n%s", i, err, sb)
+			continue
+		}
+		if !equal(p, q) {
+			t.Errorf("program %d: round trip changed the program\n%v\n%v", i, p, q)
+		}
+	}
+}
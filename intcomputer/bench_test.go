@@ -0,0 +1,65 @@
+package intcomputer
+
+import "testing"
+
+// compares its input to 8, from TestIntComputer_ExecuteJmp
+var compare8 = []int{3, 21, 1008, 21, 8, 20, 1005, 20, 22, 107, 8,
+	21, 20, 1006, 20, 31, 1106, 0, 36, 98, 0, 0, 1002, 21, 125, 20,
+	4, 20, 1105, 1, 46, 104, 999, 1105, 1, 46, 1101, 1000, 1, 20, 4,
+	20, 1105, 1, 46, 98, 99}
+
+// produces a copy of itself, from TestIntComputer_Quine
+var quine = []int{109, 1, 204, -1, 1001, 100, 1, 100, 1008, 100, 16,
+	101, 1006, 101, 0, 99}
+
+var benchmarks = []struct {
+	name    string
+	program []int
+	input   int
+}{
+	{"Countdown", countdown, 1000},
+	{"Compare8", compare8, 9},
+	{"Quine", quine, 0},
+}
+
+func benchmarkRun(b *testing.B, program []int, input int, noCache bool) {
+	c := CreateIntComputer(program, nil, nil, func(int) {})
+	c.Mem.noCache = noCache
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Reset()
+		c.QueueInput(input)
+		if _, err := c.Run(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRun(b *testing.B) {
+	for _, bm := range benchmarks {
+		b.Run(bm.name+"/Cached", func(b *testing.B) {
+			benchmarkRun(b, bm.program, bm.input, false)
+		})
+		b.Run(bm.name+"/Uncached", func(b *testing.B) {
+			benchmarkRun(b, bm.program, bm.input, true)
+		})
+	}
+}
+
+func TestIntComputer_RunAllocs(t *testing.T) {
+	c := CreateIntComputer(countdown, nil, nil, func(int) {})
+	run := func(n int) float64 {
+		return testing.AllocsPerRun(10, func() {
+			c.Reset()
+			c.QueueInput(n)
+			if _, err := c.Run(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	// executing more instructions must not allocate more
+	if short, long := run(10), run(10000); long > short {
+		t.Errorf("%v allocations for 10 iterations, %v for 10000", short, long)
+	}
+}
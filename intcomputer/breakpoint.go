@@ -0,0 +1,190 @@
+package intcomputer
+
+import "fmt"
+
+type Access int
+
+const (
+	WatchRead Access = 1 << iota
+	WatchWrite
+	WatchReadWrite = WatchRead | WatchWrite
+)
+
+// Watchpoint triggers on accesses to the addresses From through To
+type Watchpoint struct {
+	From, To int
+	Access   Access
+	// Cond, if set, must hold for the value read or about to be written.
+	// Input writes trigger regardless of Cond when their value is not
+	// queued yet, as do accesses to mapped devices, which are not read
+	// ahead.
+	Cond func(addr, v int) bool
+}
+
+// Breakpoint stops execution before the instruction at Addr, or, if Watch
+// is set, before an instruction accessing the watched memory
+type Breakpoint struct {
+	ID    int
+	Addr  int
+	Watch *Watchpoint
+}
+
+func (b *Breakpoint) String() string {
+	if b.Watch != nil {
+		return fmt.Sprintf("Watchpoint %d [%d, %d]", b.ID, b.Watch.From, b.Watch.To)
+	}
+	return fmt.Sprintf("Breakpoint %d {InsPtr: %d}", b.ID, b.Addr)
+}
+
+// AddBreakpoint stops execution before the instruction at addr and
+// returns the breakpoint's id
+func (c *IntComputer) AddBreakpoint(addr int) int {
+	c.lastBreakpointID++
+	c.breakpoints = append(c.breakpoints,
+		&Breakpoint{ID: c.lastBreakpointID, Addr: addr})
+	return c.lastBreakpointID
+}
+
+// AddWatchpoint stops execution before an instruction accessing memory
+// watched by w and returns the watchpoint's id
+func (c *IntComputer) AddWatchpoint(w Watchpoint) int {
+	c.lastBreakpointID++
+	c.breakpoints = append(c.breakpoints,
+		&Breakpoint{ID: c.lastBreakpointID, Addr: -1, Watch: &w})
+	return c.lastBreakpointID
+}
+
+// RemoveBreakpoint removes the breakpoint or watchpoint id, reporting
+// whether it existed
+func (c *IntComputer) RemoveBreakpoint(id int) bool {
+	for i, b := range c.breakpoints {
+		if b.ID == id {
+			c.breakpoints = append(c.breakpoints[:i], c.breakpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *IntComputer) ClearBreakpoints() {
+	c.breakpoints = nil
+}
+
+// access is a predicted memory access, known is false when its value can
+// not be told in advance
+type access struct {
+	MemAccess
+	known bool
+}
+
+// checkBreakpoints finds the first breakpoint triggered by the instruction
+// about to be executed
+func (c *IntComputer) checkBreakpoints() *Breakpoint {
+	var reads []access
+	var write *access
+	predicted := false
+
+	for _, b := range c.breakpoints {
+		if b.Watch == nil {
+			if b.Addr == c.InPtr {
+				return b
+			}
+			continue
+		}
+		if !predicted {
+			reads, write = c.pendingAccesses()
+			predicted = true
+		}
+
+		w := b.Watch
+		if w.Access&WatchRead != 0 {
+			for _, r := range reads {
+				if r.Addr >= w.From && r.Addr <= w.To &&
+					(w.Cond == nil || !r.known || w.Cond(r.Addr, r.Value)) {
+					return b
+				}
+			}
+		}
+		if w.Access&WatchWrite != 0 && write != nil &&
+			write.Addr >= w.From && write.Addr <= w.To &&
+			(w.Cond == nil || !write.known || w.Cond(write.Addr, write.Value)) {
+			return b
+		}
+	}
+	return nil
+}
+
+// pendingAccesses works out the memory the next instruction reads and
+// writes without executing it. Mapped devices are not read, reads of them
+// have unknown values and instructions fetched from them are not
+// predicted.
+func (c *IntComputer) pendingAccesses() (reads []access, write *access) {
+	m := c.Mem
+	code, ok, err := m.peek(c.InPtr)
+	if !ok || err != nil {
+		return nil, nil
+	}
+	ins := decode(code)
+
+	vs := make([]int, len(ins.ParamAddrModes))
+	known := true
+	for i, mode := range ins.ParamAddrModes {
+		x, ok, err := m.peek(c.InPtr + 1 + i)
+		if !ok || err != nil {
+			return reads, nil
+		}
+		addr := x
+		switch mode {
+		case Position:
+		case Immediate:
+			// write parameters are never immediate, treat as position
+			if !isWriteParam(ins.Opcode, i) {
+				vs[i] = x
+				continue
+			}
+		case Relative:
+			addr = c.RelBase + x
+		default:
+			return reads, nil
+		}
+		if isWriteParam(ins.Opcode, i) {
+			write = &access{MemAccess: MemAccess{Addr: addr}}
+			continue
+		}
+		v, ok, err := m.peek(addr)
+		if err != nil {
+			return reads, nil
+		}
+		vs[i] = v
+		known = known && ok
+		reads = append(reads, access{MemAccess{Addr: addr, Value: v}, ok})
+	}
+	if write == nil {
+		return reads, nil
+	}
+
+	write.known = known
+	switch ins.Opcode {
+	case Add:
+		write.Value, _ = c.Arithmetic.add(vs[0], vs[1])
+	case Mul:
+		write.Value, _ = c.Arithmetic.mul(vs[0], vs[1])
+	case LessThan:
+		if vs[0] < vs[1] {
+			write.Value = 1
+		}
+	case Equals:
+		if vs[0] == vs[1] {
+			write.Value = 1
+		}
+	case Input:
+		if len(c.inQueue) > 0 {
+			write.Value = c.inQueue[0]
+		} else {
+			write.known = false
+		}
+	default:
+		write.known = false
+	}
+	return reads, write
+}
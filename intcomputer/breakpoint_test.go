@@ -0,0 +1,91 @@
+package intcomputer
+
+import "testing"
+
+func TestIntComputer_Breakpoint(t *testing.T) {
+
+	// output 999 if the input value is below 8, output 1000 if
+	// the input value is equal to 8, or output 1001 if the input
+	//  value is greater than 8.
+	instructions := []int{3, 21, 1008, 21, 8, 20, 1005, 20, 22, 107, 8,
+		21, 20, 1006, 20, 31, 1106, 0, 36, 98, 0, 0, 1002, 21, 125, 20,
+		4, 20, 1105, 1, 46, 104, 999, 1105, 1, 46, 1101, 1000, 1, 20, 4,
+		20, 1105, 1, 46, 98, 99}
+	out := []int{}
+	c := CreateIntComputer(instructions, CreateLogger(), nil, func(n int) {
+		out = append(out, n)
+	})
+	c.QueueInput(7)
+
+	// the "below 8" branch
+	id := c.AddBreakpoint(31)
+	res, err := c.Run()
+	if err != nil || res.Reason != StopBreakpoint || res.InPtr != 31 ||
+		res.Breakpoint == nil || res.Breakpoint.ID != id {
+		t.Fatalf("result: %s %v expected breakpoint %d at 31", res, res.Breakpoint, id)
+	}
+	if len(out) != 0 {
+		t.Fatalf("breakpoint stopped after the instruction")
+	}
+
+	res, err = c.Resume()
+	if err != nil || res.Reason != StopHalted {
+		t.Fatalf("result: %s expected to halt", res)
+	}
+	if !equalInts(out, []int{999}) {
+		t.Errorf("output: %v expected [999]", out)
+	}
+}
+
+func TestIntComputer_Watchpoint(t *testing.T) {
+
+	// add two inputs into 11 and output the sum
+	instructions := []int{3, 11, 3, 12, 1, 11, 12, 11, 4, 11, 99, 0, 0}
+	c := CreateIntComputer(instructions, CreateLogger(), nil, func(n int) {})
+
+	tt := []struct {
+		w      Watchpoint
+		inputs []int
+		inPtrs []int
+	}{
+		// every write to 11
+		{w: Watchpoint{From: 11, To: 11, Access: WatchWrite},
+			inputs: []int{1, 2}, inPtrs: []int{0, 4}},
+		// reads of 11 or 12
+		{w: Watchpoint{From: 11, To: 12, Access: WatchRead},
+			inputs: []int{1, 2}, inPtrs: []int{4, 8}},
+		// only when a value above 10 is written
+		{w: Watchpoint{From: 0, To: 20, Access: WatchWrite,
+			Cond: func(addr, v int) bool { return v > 10 }},
+			inputs: []int{4, 20}, inPtrs: []int{2, 4}},
+	}
+
+	for i, tc := range tt {
+		c.Program(instructions)
+		c.ClearBreakpoints()
+		c.QueueInput(tc.inputs...)
+		c.AddWatchpoint(tc.w)
+
+		hits := []int{}
+		for {
+			res, err := c.Resume()
+			if err != nil {
+				t.Fatalf("case %d: %s", i, err)
+			}
+			if res.Reason == StopHalted {
+				break
+			}
+			if res.Reason != StopBreakpoint || res.Breakpoint.Watch == nil {
+				t.Fatalf("case %d: result %s", i, res)
+			}
+			hits = append(hits, res.InPtr)
+		}
+		if !equalInts(hits, tc.inPtrs) {
+			t.Errorf("case %d: stopped at %v expected %v", i, hits, tc.inPtrs)
+		}
+	}
+
+	if !c.RemoveBreakpoint(3) || c.RemoveBreakpoint(3) {
+		t.Errorf("RemoveBreakpoint")
+	}
+}
@@ -0,0 +1,313 @@
+// Package cfg recovers the control flow graph of an Intcode program.
+//
+// Code is found by following execution from address 0, and any other
+// entry points given: instructions fall through to the next one and
+// conditional jumps with an Immediate target branch to it. Jumps whose
+// target is read from memory are indirect, the code they lead to is not
+// found. Writes with a Position destination that land on code found this
+// way make the program self-modifying, its graph only describes the code
+// as loaded. Writes with a Relative destination may land anywhere, they are
+// indirect and count as possibly self-modifying.
+package cfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+// Edge kinds
+const (
+	Next     = "next"
+	Taken    = "taken"
+	NotTaken = "not taken"
+)
+
+// Block is a basic block, a run of instructions only entered at the first
+// and left after the last
+type Block struct {
+	Start int `json:"start"`
+	// End is the address after the last instruction
+	End   int                 `json:"end"`
+	Lines []*intcomputer.Line `json:"-"`
+	// Halts is set when the block ends with Halt, Invalid when it runs
+	// into words that are not an instruction
+	Halts   bool `json:"halts,omitempty"`
+	Invalid bool `json:"invalid,omitempty"`
+	// Indirect is set when the block ends with a jump to a target read
+	// from memory
+	Indirect bool `json:"indirect,omitempty"`
+}
+
+type Edge struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// CodeWrite is an instruction at At writing to the code at Addr
+type CodeWrite struct {
+	At   int `json:"at"`
+	Addr int `json:"addr"`
+}
+
+type Graph struct {
+	Blocks []*Block `json:"blocks"`
+	Edges  []Edge   `json:"edges"`
+	// Indirect lists the addresses of indirect jumps
+	Indirect   []int       `json:"indirect"`
+	CodeWrites []CodeWrite `json:"code_writes"`
+	// IndirectWrites lists the addresses of instructions writing to a
+	// Relative destination
+	IndirectWrites []int `json:"indirect_writes"`
+}
+
+// Block returns the block starting at addr, nil if there is none
+func (g *Graph) Block(addr int) *Block {
+	i := sort.Search(len(g.Blocks), func(i int) bool { return g.Blocks[i].Start >= addr })
+	if i < len(g.Blocks) && g.Blocks[i].Start == addr {
+		return g.Blocks[i]
+	}
+	return nil
+}
+
+// SelfModifying reports whether the program writes, or may write through
+// a Relative destination, to its own code
+func (g *Graph) SelfModifying() bool {
+	return len(g.CodeWrites) > 0 || len(g.IndirectWrites) > 0
+}
+
+// successors works out where execution goes after l, and whether it is
+// an indirect jump
+func successors(l *intcomputer.Line) (edges []Edge, indirect bool) {
+	next := l.Addr + l.Len()
+	switch l.Instruction.Opcode {
+	case intcomputer.Halt:
+		return nil, false
+	case intcomputer.JmpIfTrue, intcomputer.JmpIfFalse:
+	default:
+		return []Edge{{From: l.Addr, To: next, Kind: Next}}, false
+	}
+
+	cond, target := l.Operands[0], l.Operands[1]
+	taken, notTaken := true, true
+	if cond.Mode == intcomputer.Immediate {
+		jumps := cond.Value != 0
+		if l.Instruction.Opcode == intcomputer.JmpIfFalse {
+			jumps = !jumps
+		}
+		taken, notTaken = jumps, !jumps
+	}
+	if notTaken {
+		edges = append(edges, Edge{From: l.Addr, To: next, Kind: NotTaken})
+	}
+	if taken {
+		if target.Mode != intcomputer.Immediate {
+			return edges, true
+		}
+		edges = append(edges, Edge{From: l.Addr, To: target.Value, Kind: Taken})
+	}
+	return edges, false
+}
+
+// Analyze builds the control flow graph of program. Code is followed from
+// address 0 and from entries, such as the targets of indirect jumps seen
+// when running the program.
+func Analyze(program []int, entries ...int) *Graph {
+	// instructions reached, nil for addresses that do not decode
+	lines := map[int]*intcomputer.Line{}
+	succs := map[int][]Edge{}
+	indirect := map[int]bool{}
+	leaders := map[int]bool{0: true}
+	for _, e := range entries {
+		leaders[e] = true
+	}
+
+	work := append([]int{0}, entries...)
+	for len(work) > 0 {
+		addr := work[len(work)-1]
+		work = work[:len(work)-1]
+		if _, ok := lines[addr]; ok || addr < 0 || addr >= len(program) {
+			continue
+		}
+		l := intcomputer.DisassembleAt(program, addr)
+		lines[addr] = l
+		if l == nil {
+			continue
+		}
+		edges, ind := successors(l)
+		succs[addr], indirect[addr] = edges, ind
+		for _, e := range edges {
+			if e.Kind != Next {
+				leaders[e.To] = true
+			}
+			work = append(work, e.To)
+		}
+	}
+
+	g := &Graph{Edges: []Edge{}, Indirect: []int{}, CodeWrites: []CodeWrite{},
+		IndirectWrites: []int{}}
+	addrs := make([]int, 0, len(lines))
+	for a := range lines {
+		addrs = append(addrs, a)
+	}
+	sort.Ints(addrs)
+
+	// blocks start at leaders and run until the next one or a jump,
+	// walking them in order covers all code reached by falling through
+	inBlock := map[int]bool{}
+	for _, a := range addrs {
+		if inBlock[a] {
+			continue
+		}
+		b := &Block{Start: a, End: a}
+		for {
+			l := lines[b.End]
+			inBlock[b.End] = true
+			if l == nil {
+				b.Invalid = true
+				b.End++
+				break
+			}
+			b.Lines = append(b.Lines, l)
+			b.End += l.Len()
+			edges := succs[l.Addr]
+			if len(edges) != 1 || edges[0].Kind != Next || leaders[b.End] {
+				b.Halts = l.Instruction.Opcode == intcomputer.Halt
+				b.Indirect = indirect[l.Addr]
+				for _, e := range edges {
+					g.Edges = append(g.Edges, Edge{From: b.Start, To: e.To, Kind: e.Kind})
+				}
+				if b.Indirect {
+					g.Indirect = append(g.Indirect, l.Addr)
+				}
+				break
+			}
+			if _, ok := lines[b.End]; !ok {
+				// fell off the end of the program
+				break
+			}
+		}
+		g.Blocks = append(g.Blocks, b)
+	}
+	sort.Slice(g.Blocks, func(i, j int) bool { return g.Blocks[i].Start < g.Blocks[j].Start })
+
+	// words of the instructions reached
+	code := map[int]bool{}
+	for a, l := range lines {
+		code[a] = true
+		if l != nil {
+			for i := 1; i < l.Len(); i++ {
+				code[a+i] = true
+			}
+		}
+	}
+	for _, a := range addrs {
+		l := lines[a]
+		if l == nil {
+			continue
+		}
+		for _, o := range l.Operands {
+			switch {
+			case !o.Write:
+			case o.Mode == intcomputer.Relative:
+				g.IndirectWrites = append(g.IndirectWrites, a)
+			case code[o.Value]:
+				g.CodeWrites = append(g.CodeWrites, CodeWrite{At: a, Addr: o.Value})
+			}
+		}
+	}
+	return g
+}
+
+func (b *Block) MarshalJSON() ([]byte, error) {
+	type block Block
+	code := make([]string, len(b.Lines))
+	for i, l := range b.Lines {
+		code[i] = l.String()
+	}
+	return json.Marshal(struct {
+		*block
+		Code []string `json:"code"`
+	}{(*block)(b), code})
+}
+
+// WriteJSON writes the graph as JSON, with the disassembly of every block
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language. Indirect jumps
+// and self-modifying writes are drawn in red.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	sb := &strings.Builder{}
+	sb.WriteString("digraph cfg {\n")
+	sb.WriteString("\tnode [shape=box fontname=monospace];\n")
+	for _, b := range g.Blocks {
+		label := &strings.Builder{}
+		for _, l := range b.Lines {
+			label.WriteString(l.String())
+			label.WriteString(`\l`)
+		}
+		attrs := ""
+		switch {
+		case b.Invalid:
+			label.WriteString(fmt.Sprintf(`%04d: invalid instruction\l`, b.End-1))
+			attrs = " color=red"
+		case b.Indirect:
+			label.WriteString(`indirect jump\l`)
+			attrs = " color=red"
+		case b.Halts:
+			attrs = " peripheries=2"
+		}
+		fmt.Fprintf(sb, "\t\"b%d\" [label=%s%s];\n", b.Start, quote(label.String()), attrs)
+	}
+	unknown := map[int]bool{}
+	for _, e := range g.Edges {
+		if g.Block(e.To) == nil && !unknown[e.To] {
+			// a target outside the program
+			unknown[e.To] = true
+			fmt.Fprintf(sb, "\t\"b%d\" [label=\"%04d: ?\" color=red];\n", e.To, e.To)
+		}
+		attrs := ""
+		if e.Kind != Next {
+			attrs = fmt.Sprintf(" [label=%q]", e.Kind)
+		}
+		fmt.Fprintf(sb, "\t\"b%d\" -> \"b%d\"%s;\n", e.From, e.To, attrs)
+	}
+	drawn := map[[3]int]bool{}
+	for _, cw := range g.CodeWrites {
+		from, to := g.blockOf(cw.At), g.blockOf(cw.Addr)
+		if from == nil || to == nil || drawn[[3]int{from.Start, to.Start, cw.Addr}] {
+			continue
+		}
+		drawn[[3]int{from.Start, to.Start, cw.Addr}] = true
+		fmt.Fprintf(sb, "\t\"b%d\" -> \"b%d\" [label=\"writes %d\" style=dashed color=red];\n",
+			from.Start, to.Start, cw.Addr)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// blockOf returns the block holding the word at addr
+func (g *Graph) blockOf(addr int) *Block {
+	for _, b := range g.Blocks {
+		if addr >= b.Start && addr < b.End {
+			return b
+		}
+	}
+	return nil
+}
+
+// quote quotes s for DOT, keeping the \l line breaks
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
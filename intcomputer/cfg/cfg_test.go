@@ -0,0 +1,125 @@
+package cfg
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+func TestAnalyze(t *testing.T) {
+	// counts down from the input to 0, outputting every value
+	countdown := []int{3, 12, 4, 12, 1001, 12, -1, 12, 1005, 12, 2, 99, 0}
+	g := Analyze(countdown)
+
+	blocks := [][2]int{{0, 2}, {2, 11}, {11, 12}}
+	if len(g.Blocks) != len(blocks) {
+		t.Fatalf("%d blocks expected %d", len(g.Blocks), len(blocks))
+	}
+	for i, b := range g.Blocks {
+		if b.Start != blocks[i][0] || b.End != blocks[i][1] {
+			t.Errorf("block %d: [%d, %d) expected %v", i, b.Start, b.End, blocks[i])
+		}
+	}
+	if !g.Block(11).Halts || g.Block(2).Halts || len(g.Block(2).Lines) != 3 {
+		t.Errorf("blocks: %+v", g.Blocks)
+	}
+	edges := []Edge{{0, 2, Next}, {2, 11, NotTaken}, {2, 2, Taken}}
+	if len(g.Edges) != len(edges) {
+		t.Fatalf("edges %v expected %v", g.Edges, edges)
+	}
+	for i, e := range g.Edges {
+		if e != edges[i] {
+			t.Errorf("edge %d: %v expected %v", i, e, edges[i])
+		}
+	}
+	if g.SelfModifying() || len(g.Indirect) != 0 {
+		t.Errorf("self-modifying %v indirect %v", g.CodeWrites, g.Indirect)
+	}
+
+	sb := &strings.Builder{}
+	if err := g.WriteDOT(sb); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"b2" -> "b2" [label="taken"];`, `"b0" -> "b2";`,
+		`0008: JumpIfTrue 12, #2`} {
+		if !strings.Contains(sb.String(), s) {
+			t.Errorf("DOT lacks %q:\n%s", s, sb)
+		}
+	}
+
+	sb.Reset()
+	if err := g.WriteJSON(sb); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Blocks []struct {
+			Start int      `json:"start"`
+			Code  []string `json:"code"`
+		} `json:"blocks"`
+		Edges []Edge `json:"edges"`
+	}
+	if err := json.Unmarshal([]byte(sb.String()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Blocks) != 3 || decoded.Blocks[0].Code[0] != "0000: Input 12" ||
+		len(decoded.Edges) != 3 {
+		t.Errorf("JSON: %s", sb)
+	}
+}
+
+func TestAnalyze_Flags(t *testing.T) {
+	// JumpIfTrue #1, 7 jumps to the address stored at 7, the Add writes
+	// over the Halt
+	program := []int{105, 1, 7, 1101, 0, 0, 9, 3, 0, 99}
+	g := Analyze(program, 3)
+	if len(g.Indirect) != 1 || g.Indirect[0] != 0 || !g.Block(0).Indirect {
+		t.Errorf("indirect: %v", g.Indirect)
+	}
+	// the jump is always taken, so nothing follows it
+	if len(g.Edges) != 0 || g.Block(3).End != 10 || !g.Block(3).Halts {
+		t.Errorf("edges: %v blocks: %+v", g.Edges, g.Blocks)
+	}
+	if !g.SelfModifying() || g.CodeWrites[0] != (CodeWrite{At: 3, Addr: 9}) {
+		t.Errorf("code writes: %v", g.CodeWrites)
+	}
+}
+
+func TestAnalyze_RelativeWrites(t *testing.T) {
+	// Input @0 may write anywhere, relative to a base set at run time
+	program := []int{109, 5, 203, 0, 99}
+	g := Analyze(program)
+	if !g.SelfModifying() || len(g.CodeWrites) != 0 || len(g.IndirectWrites) != 1 ||
+		g.IndirectWrites[0] != 2 {
+		t.Errorf("code writes %v indirect writes %v", g.CodeWrites, g.IndirectWrites)
+	}
+}
+
+func TestAnalyze_Puzzles(t *testing.T) {
+	for _, tc := range []struct {
+		file          string
+		selfModifying bool
+		indirect      bool
+	}{
+		{"../../day5/day5-part2-input.txt", true, false},
+		{"../../day7/day7-part1-input.txt", true, true},
+	} {
+		r, err := os.Open(tc.file)
+		if err != nil {
+			t.Logf("skipping %s: %s", tc.file, err)
+			continue
+		}
+		p, err := intcomputer.ParseProgram(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", tc.file, err)
+		}
+		g := Analyze(p)
+		if g.SelfModifying() != tc.selfModifying || (len(g.Indirect) > 0) != tc.indirect {
+			t.Errorf("%s: code writes %v indirect jumps %v", tc.file, g.CodeWrites,
+				g.Indirect)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer/asm"
+)
+
+func assemble(args []string) error {
+	fs := flag.NewFlagSet("asm", flag.ExitOnError)
+	fs.Parse(args)
+
+	var src []byte
+	var err error
+	if path := fs.Arg(0); path == "" || path == "-" {
+		src, err = io.ReadAll(os.Stdin)
+	} else {
+		src, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	program, err := asm.Assemble(string(src))
+	if err != nil {
+		return err
+	}
+	words := make([]string, len(program))
+	for i, w := range program {
+		words[i] = fmt.Sprintf("%d", w)
+	}
+	_, err = fmt.Println(strings.Join(words, ","))
+	return err
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer/cfg"
+)
+
+func graph(args []string) error {
+	fs := flag.NewFlagSet("cfg", flag.ExitOnError)
+	format := fs.String("format", "dot", "output format, dot or json")
+	var entries inputs
+	fs.Var(&entries, "entry", "comma separated addresses to follow code from besides 0")
+	fs.Parse(args)
+
+	program, err := readProgram(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	g := cfg.Analyze(program, entries...)
+	w := bufio.NewWriter(os.Stdout)
+	switch *format {
+	case "dot":
+		err = g.WriteDOT(w)
+	case "json":
+		err = g.WriteJSON(w)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+// runInputs is a flag that may be repeated, each giving the inputs of a run
+type runInputs []inputs
+
+func (r *runInputs) String() string {
+	words := make([]string, len(*r))
+	for i, in := range *r {
+		words[i] = in.String()
+	}
+	return strings.Join(words, " ")
+}
+
+func (r *runInputs) Set(s string) error {
+	var in inputs
+	if err := in.Set(s); err != nil {
+		return err
+	}
+	*r = append(*r, in)
+	return nil
+}
+
+// files is a flag that may be repeated, each giving a file name
+type files []string
+
+func (f *files) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *files) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+func readCoverage(path string) (*intcomputer.Coverage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return intcomputer.ReadCoverage(f)
+}
+
+func cover(args []string) error {
+	fs := flag.NewFlagSet("cover", flag.ExitOnError)
+	var runs runInputs
+	fs.Var(&runs, "in", "comma separated inputs of a run, repeat for more runs")
+	var merge files
+	fs.Var(&merge, "merge", "coverage file written by -json to merge in, may be repeated")
+	jsonPath := fs.String("json", "", "also write the merged coverage as JSON to this file")
+	fs.Parse(args)
+
+	program, err := readProgram(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	cv := intcomputer.NewCoverage()
+	for _, path := range merge {
+		o, err := readCoverage(path)
+		if err != nil {
+			return err
+		}
+		cv.Merge(o)
+	}
+	if len(runs) == 0 && len(merge) == 0 {
+		runs = append(runs, nil)
+	}
+	for _, in := range runs {
+		out, err := runProgram(program, in, cv)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "input: %v output: %v\n", []int(in), out)
+	}
+
+	if *jsonPath != "" {
+		f, err := os.Create(*jsonPath)
+		if err != nil {
+			return err
+		}
+		if err := cv.WriteJSON(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if err := cv.WriteListing(w, program); err != nil {
+		return err
+	}
+	return w.Flush()
+}
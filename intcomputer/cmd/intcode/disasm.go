@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"os"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+func disasm(args []string) error {
+	fs := flag.NewFlagSet("disasm", flag.ExitOnError)
+	fs.Parse(args)
+
+	program, err := readProgram(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if err := intcomputer.WriteListing(w, program); err != nil {
+		return err
+	}
+	return w.Flush()
+}
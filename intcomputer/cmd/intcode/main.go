@@ -0,0 +1,68 @@
+// Command intcode is a toolbox for Intcode programs.
+//
+//	intcode <command> [flags] [file]
+//
+// Programs, or assembly sources for asm, are read from the file, or from
+// stdin when it is omitted or -.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"asm":       {usage: "assemble a source file into a program", run: assemble},
+	"cfg":       {usage: "print the control flow graph of a program", run: graph},
+	"cover":     {usage: "run a program and list which code was covered", run: cover},
+	"disasm":    {usage: "print an annotated listing of a program", run: disasm},
+	"profile":   {usage: "run a program and report where it spends its time", run: profile},
+	"transpile": {usage: "translate a program to Go", run: transpileCmd},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: intcode <command> [flags] [file]\n\ncommands:\n")
+	names := make([]string, 0, len(commands))
+	for n := range commands {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", n, commands[n].usage)
+	}
+	os.Exit(2)
+}
+
+func readProgram(path string) ([]int, error) {
+	if path == "" || path == "-" {
+		return intcomputer.ParseProgram(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return intcomputer.ParseProgram(f)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "intcode %s: %s\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
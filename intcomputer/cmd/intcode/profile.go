@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+func profile(args []string) error {
+	fs := flag.NewFlagSet("profile", flag.ExitOnError)
+	var in inputs
+	fs.Var(&in, "in", "comma separated program inputs")
+	top := fs.Int("top", 10, "number of hottest addresses to list")
+	jsonPath := fs.String("json", "", "also write the profile as JSON to this file")
+	fs.Parse(args)
+
+	program, err := readProgram(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	p := intcomputer.NewProfile()
+	out, err := runProgram(program, in, p)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "output: %v\n", out)
+
+	if *jsonPath != "" {
+		f, err := os.Create(*jsonPath)
+		if err != nil {
+			return err
+		}
+		if err := p.WriteJSON(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if err := p.WriteReport(w, program, *top); err != nil {
+		return err
+	}
+	return w.Flush()
+}
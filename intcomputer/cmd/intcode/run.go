@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+// inputs is a flag holding a comma separated list of program inputs
+type inputs []int
+
+func (in *inputs) String() string {
+	words := make([]string, len(*in))
+	for i, v := range *in {
+		words[i] = strconv.Itoa(v)
+	}
+	return strings.Join(words, ",")
+}
+
+func (in *inputs) Set(s string) error {
+	*in = (*in)[:0]
+	for _, w := range strings.Split(s, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(w))
+		if err != nil {
+			return err
+		}
+		*in = append(*in, v)
+	}
+	return nil
+}
+
+// runProgram runs program to completion on in, handing every instruction
+// to t, and returns its outputs
+func runProgram(program []int, in []int, t intcomputer.Tracer) ([]int, error) {
+	out := []int{}
+	c := intcomputer.CreateIntComputer(program, nil, nil, func(v int) {
+		out = append(out, v)
+	})
+	c.Tracer = t
+	c.QueueInput(in...)
+	res, err := c.Run()
+	if err != nil {
+		return out, err
+	}
+	if res.Reason != intcomputer.StopHalted {
+		return out, fmt.Errorf("program stopped: %s", res)
+	}
+	return out, nil
+}
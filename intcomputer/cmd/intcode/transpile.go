@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer/transpile"
+)
+
+func transpileCmd(args []string) error {
+	fs := flag.NewFlagSet("transpile", flag.ExitOnError)
+	pkg := fs.String("pkg", "main", "package of the generated file, main adds a main function")
+	fn := fs.String("func", "Run", "name of the generated function")
+	outPath := fs.String("o", "", "file to write, stdout if empty")
+	var runs runInputs
+	fs.Var(&runs, "in", "comma separated inputs of a run recording jump targets, may be repeated")
+	var entries inputs
+	fs.Var(&entries, "entry", "comma separated addresses to translate code from besides 0")
+	fs.Parse(args)
+
+	program, err := readProgram(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// runs that stop for more input than given still count
+	targets := transpile.Targets{}
+	for _, in := range runs {
+		c := intcomputer.CreateIntComputer(program, nil, nil, func(int) {})
+		c.Tracer = targets
+		c.QueueInput(in...)
+		if _, err := c.Run(); err != nil {
+			return err
+		}
+	}
+	entries = append(entries, targets.Entries()...)
+
+	w := os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return transpile.Generate(w, program, transpile.Options{
+		Package: *pkg,
+		Func:    *fn,
+		Entries: entries,
+	})
+}
@@ -0,0 +1,238 @@
+package intcomputer
+
+import "fmt"
+
+// Engine selects how a computer executes instructions
+type Engine int
+
+const (
+	// EngineInterpreter fetches and decodes every instruction it executes
+	EngineInterpreter Engine = iota
+	// EngineCompiled translates the straight line code between jumps into
+	// chains of closures, with parameter words resolved ahead of time.
+	// Blocks that the program writes to are dropped and interpreted from
+	// then on. Computers with a Tracer or breakpoints always interpret.
+	EngineCompiled
+)
+
+func (e Engine) String() string {
+	switch e {
+	case EngineInterpreter:
+		return "Interpreter"
+	case EngineCompiled:
+		return "Compiled"
+	}
+	return fmt.Sprintf("Engine(%d)", int(e))
+}
+
+// engine of new computers, the tests switch it to run everything compiled
+var defaultEngine = EngineInterpreter
+
+// blocks stop at jumps or after this many instructions
+const maxBlockOps = 64
+
+// param resolves a parameter: its value, or its address for parameters
+// that are written to
+type param func(c *IntComputer) (int, error)
+
+type compiledOp struct {
+	at, next int
+	code     int
+	ins      *Instruction
+	op       *opcode
+	params   []param
+}
+
+func (o *compiledOp) run(c *IntComputer) error {
+	params := c.params[:len(o.params)]
+	for i, p := range o.params {
+		v, err := p(c)
+		if err != nil {
+			c.InPtr = o.at
+			return c.locate(err, o.code, o.ins)
+		}
+		params[i] = v
+	}
+	return c.dispatch(o.op, params, o.at, o.code, o.ins)
+}
+
+type block struct {
+	start, end int
+	// words the block was compiled from and the load they were in
+	words []int
+	gen   int
+	ops   []compiledOp
+}
+
+type compiler struct {
+	blocks map[int]*block
+	// start addresses left to the interpreter
+	interp map[int]bool
+	gen    int
+}
+
+func compileParam(mode, v int, write bool) param {
+	switch {
+	case write && mode == Relative:
+		return func(c *IntComputer) (int, error) { return c.RelBase + v, nil }
+	case write, mode == Immediate:
+		return func(c *IntComputer) (int, error) { return v, nil }
+	case mode == Relative:
+		return func(c *IntComputer) (int, error) { return c.Mem.readAddress(c.RelBase + v) }
+	}
+	return func(c *IntComputer) (int, error) { return c.Mem.readAddress(v) }
+}
+
+// compileBlock compiles the code at addr up to the first jump, nil if
+// there is no well formed instruction there
+func (c *IntComputer) compileBlock(addr int) *block {
+	m := c.Mem
+	b := &block{start: addr, end: addr, gen: m.gen}
+	for len(b.ops) < maxBlockOps && b.end >= 0 && b.end < m.Size() {
+		ins := decodeAt(m.storage, b.end)
+		if ins == nil || m.mapped(b.end, b.end+1+len(ins.ParamAddrModes)) {
+			// code on devices is interpreted
+			break
+		}
+		o := compiledOp{
+			at:     b.end,
+			next:   b.end + 1 + len(ins.ParamAddrModes),
+			code:   m.storage[b.end],
+			ins:    ins,
+			op:     lookup(ins.Opcode),
+			params: make([]param, len(ins.ParamAddrModes)),
+		}
+		for i, mode := range ins.ParamAddrModes {
+			o.params[i] = compileParam(mode, m.storage[b.end+1+i],
+				isWriteParam(ins.Opcode, i))
+		}
+		b.ops = append(b.ops, o)
+		b.end = o.next
+		if op := ins.Opcode; op == JmpIfTrue || op == JmpIfFalse || op == Halt {
+			break
+		}
+	}
+	if len(b.ops) == 0 {
+		return nil
+	}
+	b.words = copyInts(m.storage[b.start:b.end])
+
+	if len(m.code) < b.end {
+		m.code = append(m.code, make([]int32, b.end-len(m.code))...)
+	}
+	for a := b.start; a < b.end; a++ {
+		m.code[a]++
+	}
+	return b
+}
+
+// current reports whether memory still holds the words b was compiled from
+func (b *block) current(m *Memory) bool {
+	for i, w := range b.words {
+		if v, err := m.readAddress(b.start + i); err != nil || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *compiler) drop(m *Memory, b *block) {
+	delete(e.blocks, b.start)
+	for a := b.start; a < b.end; a++ {
+		m.code[a]--
+	}
+}
+
+// invalidate drops the blocks the program wrote to, which are interpreted
+// from then on
+func (e *compiler) invalidate(m *Memory) {
+	for _, a := range m.codeWrites {
+		for start, b := range e.blocks {
+			if a >= b.start && a < b.end {
+				e.drop(m, b)
+				e.interp[start] = true
+			}
+		}
+	}
+	m.codeWrites = m.codeWrites[:0]
+}
+
+// blockAt returns the compiled block starting at addr, nil if the code
+// there is to be interpreted
+func (c *IntComputer) blockAt(addr int) *block {
+	e := c.compiled
+	if e == nil {
+		e = &compiler{blocks: map[int]*block{}, interp: map[int]bool{}, gen: c.Mem.gen}
+		c.compiled = e
+	}
+	m := c.Mem
+	if len(m.codeWrites) > 0 {
+		e.invalidate(m)
+	}
+	if e.gen != m.gen {
+		// a new program, or the same one reloaded
+		clear(e.interp)
+		e.gen = m.gen
+	}
+	if e.interp[addr] {
+		return nil
+	}
+
+	b := e.blocks[addr]
+	if b != nil && b.gen != m.gen {
+		if b.current(m) {
+			b.gen = m.gen
+		} else {
+			e.drop(m, b)
+			b = nil
+		}
+	}
+	if b == nil {
+		if b = c.compileBlock(addr); b == nil {
+			e.interp[addr] = true
+			return nil
+		}
+		e.blocks[addr] = b
+	}
+	return b
+}
+
+// useCompiled reports whether the computer runs compiled code
+func (c *IntComputer) useCompiled() bool {
+	return c.Engine == EngineCompiled && c.Tracer == nil && len(c.breakpoints) == 0
+}
+
+// runCompiled executes at most max instructions of the block at InPtr, or
+// interprets one instruction if there is none, and returns how many it
+// executed
+func (c *IntComputer) runCompiled(max int) (int, error) {
+	b := c.blockAt(c.InPtr)
+	if b == nil {
+		if err := c.executeStep(nil); err != nil || c.IsAwaitingInput() {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	n := 0
+	for i := range b.ops {
+		if n >= max {
+			break
+		}
+		o := &b.ops[i]
+		if err := o.run(c); err != nil {
+			return n, err
+		}
+		if c.IsAwaitingInput() {
+			break
+		}
+		n++
+		c.executed++
+		c.resuming = false
+		// stop on halt or break, jumps and writes to compiled code
+		if c.flags != 0 || c.InPtr != o.next || len(c.Mem.codeWrites) > 0 {
+			break
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,131 @@
+package intcomputer
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// TestMain runs every test with the interpreter, then again compiled.
+// Benchmarks pick their engine and only run once.
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if code != 0 || flag.Lookup("test.bench").Value.String() != "" {
+		os.Exit(code)
+	}
+	defaultEngine = EngineCompiled
+	os.Exit(m.Run())
+}
+
+// runEngine runs program with e from the registers inPtr and relBase
+func runEngine(t *testing.T, e Engine, program []int, inPtr, relBase int,
+	in ...int) ([]int, RunResult) {
+	t.Helper()
+	out := []int{}
+	c := CreateIntComputer(program, nil, nil, func(v int) {
+		out = append(out, v)
+	})
+	c.Engine = e
+	c.InPtr, c.RelBase = inPtr, relBase
+	c.QueueInput(in...)
+	res, err := c.Run()
+	if err != nil {
+		t.Fatalf("%s: %s", e, err)
+	}
+	return out, res
+}
+
+func TestEngineCompiled(t *testing.T) {
+	tt := []struct {
+		name    string
+		program []int
+		in      []int
+		// registers set before running
+		inPtr, relBase int
+	}{
+		{"countdown", countdown, []int{100}, 0, 0},
+		{"compare8", compare8, []int{8}, 0, 0},
+		{"quine", quine, nil, 0, 0},
+		// reads an input to 6 and outputs it, relative to the base set
+		{"registers", []int{99, 203, 0, 204, 0, 99, 0}, []int{42}, 1, 6},
+		// turns the Add at 4 into a Mul in the same block, outputs 12
+		{"self-modifying", []int{
+			1101, 1100, 2, 4, // 0: Add #1100, #2, 4
+			1101, 3, 4, 13, // 4: Add #3, #4, 13 -> Mul #3, #4, 13
+			4, 13, // 8: Output 13
+			99, 0, 0, 0,
+		}, nil, 0, 0},
+	}
+	for _, tc := range tt {
+		want, wantRes := runEngine(t, EngineInterpreter, tc.program, tc.inPtr, tc.relBase, tc.in...)
+		got, res := runEngine(t, EngineCompiled, tc.program, tc.inPtr, tc.relBase, tc.in...)
+		if !equalInts(got, want) || res != wantRes {
+			t.Errorf("%s: compiled %v %s, interpreted %v %s", tc.name, got, res,
+				want, wantRes)
+		}
+	}
+}
+
+func TestEngineCompiled_Fallback(t *testing.T) {
+	// writes into the block it runs in: the Output at 4 becomes an
+	// AdjustRelBase
+	program := []int{1101, 5, 104, 4, 4, 2, 99}
+	c := CreateIntComputer(program, nil, nil, func(int) {
+		t.Error("stale block output")
+	})
+	c.Engine = EngineCompiled
+	res, err := c.Run()
+	if err != nil || res.Reason != StopHalted || c.RelBase != 2 {
+		t.Fatalf("result %s err %v relative base %d", res, err, c.RelBase)
+	}
+	if !c.compiled.interp[0] || c.compiled.blocks[0] != nil {
+		t.Errorf("modified block still compiled")
+	}
+
+	// reloading the program compiles it again
+	c.Reset()
+	c.OutFunc = func(int) {}
+	c.Store(104, 0)
+	if res, err = c.RunFor(1); err != nil || res.Steps != 1 || c.compiled.interp[0] {
+		t.Errorf("result %s err %v interpreted %v", res, err, c.compiled.interp[0])
+	}
+}
+
+func TestEngineCompiled_Stops(t *testing.T) {
+	c := CreateIntComputer(countdown, nil, nil, func(int) {})
+	c.Engine = EngineCompiled
+	c.QueueInput(50)
+	for _, n := range []int{1, 5, 7} {
+		if res, err := c.RunFor(n); err != nil || res.Steps != n {
+			t.Fatalf("RunFor(%d): %s %v", n, res, err)
+		}
+	}
+	if res, _ := c.Run(); res.Executed != 1+3*50+1 {
+		t.Errorf("executed %d", res.Executed)
+	}
+
+	// faults leave the computer at the faulting instruction
+	c.Program([]int{1101, 1, 1, 8, 1, -1, 0, 0})
+	if _, err := c.Run(); err == nil || c.InPtr != 4 || c.executed != 1 {
+		t.Errorf("err %v InPtr %d executed %d", err, c.InPtr, c.executed)
+	}
+}
+
+func BenchmarkRunCompiled(b *testing.B) {
+	for _, bm := range benchmarks {
+		for _, e := range []Engine{EngineInterpreter, EngineCompiled} {
+			b.Run(bm.name+"/"+e.String(), func(b *testing.B) {
+				c := CreateIntComputer(bm.program, nil, nil, func(int) {})
+				c.Engine = e
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					c.Reset()
+					c.QueueInput(bm.input)
+					if _, err := c.Run(); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -1,184 +1,198 @@
 package intcomputer
 
-import (
-	"fmt"
-)
-
 const (
 	// Addressing Modes
 	Position  = 0
 	Immediate = 1
+	Relative  = 2
 
 	// Instructions
-	Unsupported = -1
-	Add         = 1
-	Mul         = 2
-	Input       = 3
-	Output      = 4
-	JmpIfTrue   = 5
-	JmpIfFalse  = 6
-	LessThan    = 7
-	Equals      = 8
-	Halt        = 99
+	Unsupported   = -1
+	Add           = 1
+	Mul           = 2
+	Input         = 3
+	Output        = 4
+	JmpIfTrue     = 5
+	JmpIfFalse    = 6
+	LessThan      = 7
+	Equals        = 8
+	AdjustRelBase = 9
+	Halt          = 99
 )
 
 type InputMethod func() int
 
 type OutputMethod func(int)
 
-type Logger struct {
-	buffer []string
-}
-
-func CreateLogger() *Logger {
-	return &Logger{buffer: []string{}}
-}
-func (l *Logger) log(msg string) {
-	l.buffer = append(l.buffer, msg)
-}
-
-func (l *Logger) Logs() []string {
-	ret := make([]string, len(l.buffer))
-	copy(ret, l.buffer)
-	return ret
-}
-
-func (l *Logger) clear() {
-	l.buffer = []string{}
-}
-
 type IntComputer struct {
 	Mem     *Memory
 	InPtr   int
+	RelBase int
 	InFunc  InputMethod
 	OutFunc OutputMethod
-	logger  *Logger
+	logger  Logger
 
-	//  xxxx xxxx xxxx b3 b2 Break Halt
+	// the program as loaded, never modified
+	image []int
+
+	// channel backed I/O while running from RunContext
+	source func() (int, error)
+	sink   func(int) error
+
+	// Arithmetic selects what Add and Mul do on overflow
+	Arithmetic Arithmetic
+
+	// Tracer, if set, receives every executed instruction
+	Tracer Tracer
+
+	// Engine selects how instructions are executed
+	Engine   Engine
+	compiled *compiler
+
+	// Limits bound every run
+	Limits     Limits
+	runOutputs int
+
+	// instructions executed since the program was loaded
+	executed int
+
+	// record of the instruction being executed by Step
+	rec *StepInfo
+	// parameters of the instruction being executed
+	params [maxParams]int
+
+	breakpoints      []*Breakpoint
+	lastBreakpointID int
+	// set when stopped at a breakpoint, which is not checked again when
+	// resuming at resumeAt
+	resuming bool
+	resumeAt int
+
+	// inputs queued with QueueInput, consumed before any other source
+	inQueue []int
+
+	//  xxxx xxxx xxxx b3 AwaitingInput Break Halt
 	flags uint16
 }
 
+// readParams resolves the parameters of ins into a buffer that is reused
+// by the next instruction
 func (c *IntComputer) readParams(ins *Instruction) ([]int, error) {
-	ret := make([]int, len(ins.ParamAddrModes))
-	for i, m := range ins.ParamAddrModes {
-		v, err := c.Mem.read(m, i+1)
+	ret := c.params[:len(ins.ParamAddrModes)]
+	for i := range ins.ParamAddrModes {
+		v, err := c.readParam(ins, i)
 		if err != nil {
 			return ret, err
 		}
 		ret[i] = v
 	}
+	if c.rec != nil {
+		c.rec.Operands = append(c.rec.Operands, ret...)
+	}
 	return ret, nil
 }
 
-func (c *IntComputer) storeResult(v, ptr int) error {
-	return c.Mem.write(v, ptr)
-}
-
-func (c *IntComputer) jmpIfTrue(ins *Instruction) error {
-	params, err := c.readParams(ins)
-	if err != nil {
-		return err
+func (c *IntComputer) readParam(ins *Instruction, i int) (int, error) {
+	m, ptr := ins.ParamAddrModes[i], c.InPtr+1+i
+	if isWriteParam(ins.Opcode, i) {
+		// param resolves to the address to store results to
+		return c.Mem.paramAddress(m, ptr, c.RelBase)
 	}
-	v, ptr := params[0], params[1]
-	if v != 0 {
-		c.InPtr = ptr
-	} else {
-		c.InPtr += 3
+	if m == Immediate {
+		return c.Mem.readAddress(ptr)
 	}
-	return err
-}
-
-func (c *IntComputer) jmpIfFalse(ins *Instruction) error {
-	params, err := c.readParams(ins)
+	addr, err := c.Mem.paramAddress(m, ptr, c.RelBase)
 	if err != nil {
-		return err
-	}
-	v, ptr := params[0], params[1]
-	if v == 0 {
-		c.InPtr = ptr
-	} else {
-		c.InPtr += 3
+		return -1, err
 	}
-	return err
-}
-
-func (c *IntComputer) lt(ins *Instruction) error {
-	params, err := c.readParams(ins)
+	v, err := c.Mem.readAddress(addr)
 	if err != nil {
-		return err
+		return -1, err
 	}
-	if params[0] < params[1] {
-		err = c.Mem.write(1, params[2])
-	} else {
-		err = c.Mem.write(0, params[2])
+	if c.rec != nil {
+		c.rec.Reads = append(c.rec.Reads, MemAccess{Addr: addr, Value: v})
 	}
-	c.InPtr += 4
-	return err
+	return v, nil
 }
 
-func (c *IntComputer) eq(ins *Instruction) error {
-	params, err := c.readParams(ins)
-	if err != nil {
+func (c *IntComputer) storeResult(v, ptr int) error {
+	if err := c.Mem.write(v, ptr); err != nil {
 		return err
 	}
-	if params[0] == params[1] {
-		err = c.Mem.write(1, params[2])
-	} else {
-		err = c.Mem.write(0, params[2])
+	if c.rec != nil {
+		c.rec.Writes = append(c.rec.Writes, MemAccess{Addr: ptr, Value: v})
 	}
-	c.InPtr += 4
-	return err
+	return nil
 }
 
-func (c *IntComputer) add(ins *Instruction) error {
-	params, err := c.readParams(ins)
-	if err != nil {
-		return err
+func jmpIfTrue(c *IntComputer, params []int) error {
+	if params[0] != 0 {
+		c.InPtr = params[1]
 	}
-	if err := c.storeResult(params[0]+params[1], params[2]); err != nil {
-		return err
+	return nil
+}
+
+func jmpIfFalse(c *IntComputer, params []int) error {
+	if params[0] == 0 {
+		c.InPtr = params[1]
 	}
-	c.InPtr += 4
 	return nil
 }
 
-func (c *IntComputer) mul(ins *Instruction) error {
-	params, err := c.readParams(ins)
-	if err != nil {
-		return err
+func lt(c *IntComputer, params []int) error {
+	if params[0] < params[1] {
+		return c.storeResult(1, params[2])
 	}
-	if err := c.storeResult(params[0]*params[1], params[2]); err != nil {
-		return err
+	return c.storeResult(0, params[2])
+}
+
+func eq(c *IntComputer, params []int) error {
+	if params[0] == params[1] {
+		return c.storeResult(1, params[2])
 	}
-	c.InPtr += 4
-	return nil
+	return c.storeResult(0, params[2])
 }
 
-func (c *IntComputer) input() error {
-	ptr, err := c.Mem.read(Immediate, 1)
+func add(c *IntComputer, params []int) error {
+	v, err := c.Arithmetic.add(params[0], params[1])
 	if err != nil {
 		return err
 	}
-	v := c.InFunc()
-	err = c.Mem.write(v, ptr)
+	return c.storeResult(v, params[2])
+}
+
+func mul(c *IntComputer, params []int) error {
+	v, err := c.Arithmetic.mul(params[0], params[1])
 	if err != nil {
 		return err
 	}
-	c.InPtr += 2
-	return err
+	return c.storeResult(v, params[2])
 }
 
-func (c *IntComputer) output(ins *Instruction) error {
-	v, err := c.Mem.read(ins.ParamAddrModes[0], 1)
+func input(c *IntComputer, params []int) error {
+	v, err := c.ReadInput()
 	if err != nil {
 		return err
 	}
-	c.OutFunc(v)
-	c.InPtr += 2
-	return err
+	return c.storeResult(v, params[0])
+}
+
+func output(c *IntComputer, params []int) error {
+	return c.WriteOutput(params[0])
+}
+
+func adjustRelBase(c *IntComputer, params []int) error {
+	c.RelBase += params[0]
+	return nil
 }
 
-func (c *IntComputer) halt() {
+func halt(c *IntComputer, params []int) error {
+	c.Halt()
+	return nil
+}
+
+// Halt stops the computer at the current instruction, for good
+func (c *IntComputer) Halt() {
 	// set flag-0th bit of
 	c.flags |= 0x01
 }
@@ -191,60 +205,75 @@ func (c *IntComputer) isBreak() bool {
 	return ((c.flags >> 1) & 0x01) != 0
 }
 
+// IsAwaitingInput reports whether the computer is suspended at an Input
+// instruction for lack of input
+func (c *IntComputer) IsAwaitingInput() bool {
+	return ((c.flags >> 2) & 0x01) != 0
+}
+
 func (c *IntComputer) execute() error {
-	code, err := c.Mem.opcodeFetch()
+	code, ins, err := c.Mem.fetchInstruction(c.InPtr)
 	if err != nil {
-		return err
+		return c.locate(err, code, nil)
 	}
-	ins := decode(code)
-
-	c.logger.log(fmt.Sprintf("[IntComputer] {InsPtr: %d} Execute: %v",
-		c.InPtr, ins))
-	switch ins.Opcode {
-	case Add:
-		c.add(ins)
-	case Mul:
-		c.mul(ins)
-	case Input:
-		c.input()
-	case Output:
-		c.output(ins)
-	case JmpIfTrue:
-		c.jmpIfTrue(ins)
-	case JmpIfFalse:
-		c.jmpIfFalse(ins)
-	case LessThan:
-		c.lt(ins)
-	case Equals:
-		c.eq(ins)
-	case Halt:
-		c.halt()
-	default:
-		return fmt.Errorf("Unsupported opcode")
+	if c.rec != nil {
+		c.rec.Code, c.rec.Instruction = code, ins
+	}
+
+	op := lookup(ins.Opcode)
+	if op == nil {
+		return c.locate(&ErrUnsupportedOpcode{}, code, ins)
+	}
+	params, err := c.readParams(ins)
+	if err != nil {
+		return c.locate(err, code, ins)
 	}
-	c.Mem.memPtr = c.InPtr
-	return err
+
+	return c.dispatch(op, params, c.InPtr, code, ins)
+}
+
+// dispatch runs the handler of the instruction at address at, with its
+// parameters resolved
+func (c *IntComputer) dispatch(op *opcode, params []int, at, code int,
+	ins *Instruction) error {
+	c.InPtr = at + 1 + len(params)
+	err := op.Exec(c, params)
+	switch {
+	case err == ErrNoInput:
+		// suspend at this instruction until input is queued
+		c.flags |= 0b100
+		c.InPtr = at
+	case err != nil:
+		c.InPtr = at
+		return c.locate(err, code, ins)
+	case c.IsHalted():
+		c.InPtr = at
+	}
+	return nil
 }
 
-func CreateIntComputer(instructions []int, logger *Logger,
+func CreateIntComputer(instructions []int, logger Logger,
 	in InputMethod, out OutputMethod) *IntComputer {
-	ins := make([]int, len(instructions))
-	copy(ins, instructions)
+	image := copyInts(instructions)
 	return &IntComputer{
-		Mem:     &Memory{storage: ins, memPtr: 0, logger: logger},
+		Mem:     &Memory{storage: copyInts(image), logger: logger},
 		InPtr:   0,
 		InFunc:  in,
 		OutFunc: out,
 		logger:  logger,
+		image:   image,
+		Engine:  defaultEngine,
 	}
 }
 
+// Store writes val to memory at ptr. Handlers of custom instructions store
+// their results with it.
 func (c *IntComputer) Store(val, ptr int) error {
-	return c.Mem.write(val, ptr)
+	return c.storeResult(val, ptr)
 }
 
 func (c *IntComputer) ReadMemory(ptr, n int) ([]int, error) {
-	ret := make([]int, 5)
+	ret := make([]int, n)
 	for i := 0; i < n; i++ {
 		v, err := c.Mem.readAddress(ptr + i)
 		if err != nil {
@@ -252,35 +281,41 @@ func (c *IntComputer) ReadMemory(ptr, n int) ([]int, error) {
 		}
 		ret[i] = v
 	}
-	return ret[:n], nil
+	return ret, nil
 }
 
-func (c *IntComputer) Run() error {
-	var err error
-	for !c.IsHalted() && !c.isBreak() && err == nil {
-		err = c.execute()
-	}
-	return err
+func (c *IntComputer) Run() (RunResult, error) {
+	return c.run(nil, nil, -1)
 }
 
+// RunFor is Run executing at most n instructions
+func (c *IntComputer) RunFor(n int) (RunResult, error) {
+	return c.run(nil, nil, n)
+}
+
+// Reset restarts the program the computer was created or last loaded
+// with, from a pristine copy of it and with no pending input
 func (c *IntComputer) Reset() {
-	c.Mem = &Memory{storage: []int{99}, memPtr: 0, logger: c.logger}
+	c.Mem.load(copyInts(c.image))
 	c.flags = 0
+	c.inQueue = nil
+	c.resuming = false
+	c.executed = 0
 	c.InPtr = 0
-
-	c.logger.clear()
+	c.RelBase = 0
 }
 
+// Program loads a copy of instructions, which Reset restores from then on
 func (c *IntComputer) Program(instructions []int) {
+	c.image = copyInts(instructions)
 	c.Reset()
-	c.Mem = &Memory{storage: instructions, memPtr: 0, logger: c.logger}
 }
 
 func (c *IntComputer) Break() {
 	c.flags |= 0b10
 }
 
-func (c *IntComputer) Resume() error {
-	c.flags &= 0xfffd
+func (c *IntComputer) Resume() (RunResult, error) {
+	c.flags &= 0xfff9
 	return c.Run()
 }
@@ -0,0 +1,64 @@
+package intcomputer
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrInputClosed = errors.New("Input channel closed")
+
+// RunContext runs the program, reading inputs from in and writing outputs
+// to out, until it halts, breaks, fails or ctx is cancelled. InFunc and
+// OutFunc are not used meanwhile and out is not closed on return. A
+// cancelled run returns ctx.Err() and leaves the computer at the
+// instruction it was about to execute, ready to be resumed.
+func (c *IntComputer) RunContext(ctx context.Context, in <-chan int,
+	out chan<- int) (RunResult, error) {
+	parent := ctx
+	if c.Limits.Timeout > 0 {
+		// to interrupt blocking channel I/O
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Limits.Timeout)
+		defer cancel()
+	}
+	done := ctx.Done()
+	c.source = func() (int, error) {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				return 0, ErrInputClosed
+			}
+			return v, nil
+		case <-done:
+			return 0, ctx.Err()
+		}
+	}
+	c.sink = func(v int) error {
+		select {
+		case out <- v:
+			return nil
+		case <-done:
+			return ctx.Err()
+		}
+	}
+	defer func() {
+		c.source, c.sink = nil, nil
+	}()
+	// input is never starved when read from a channel
+	c.flags &= 0xfffb
+
+	return c.run(ctx, parent, -1)
+}
+
+// Start runs RunContext in its own goroutine. The returned channel
+// receives its result once the computer stops.
+func (c *IntComputer) Start(ctx context.Context, in <-chan int,
+	out chan<- int) <-chan RunResult {
+	ret := make(chan RunResult, 1)
+	go func() {
+		res, _ := c.RunContext(ctx, in, out)
+		ret <- res
+		close(ret)
+	}()
+	return ret
+}
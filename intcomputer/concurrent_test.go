@@ -0,0 +1,71 @@
+package intcomputer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIntComputer_Start(t *testing.T) {
+
+	// read an input, double it, output it and repeat until a 0 is read
+	instructions := []int{3, 15, 1006, 15, 14, 102, 2, 15, 15, 4, 15, 1105,
+		1, 0, 99, 0}
+
+	ctx := context.Background()
+	in, mid, out := make(chan int), make(chan int), make(chan int, 1)
+
+	// two doublers wired back to back
+	a := CreateIntComputer(instructions, CreateLogger(), nil, nil)
+	b := CreateIntComputer(instructions, CreateLogger(), nil, nil)
+	aDone, bDone := a.Start(ctx, in, mid), b.Start(ctx, mid, out)
+
+	for _, x := range []int{1, 5, -3} {
+		in <- x
+		if v := <-out; v != 4*x {
+			t.Errorf("output: %d expected %d", v, 4*x)
+		}
+	}
+	in <- 0
+	if res := <-aDone; res.Reason != StopHalted {
+		t.Errorf("first computer: %s", res)
+	}
+	mid <- 0
+	if res := <-bDone; res.Reason != StopHalted {
+		t.Errorf("second computer: %s", res)
+	}
+	if !a.IsHalted() || !b.IsHalted() {
+		t.Errorf("computers did not halt")
+	}
+}
+
+func TestIntComputer_RunContextCancel(t *testing.T) {
+
+	instructions := []int{3, 5, 4, 5, 99, 0}
+	c := CreateIntComputer(instructions, CreateLogger(), nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in, out := make(chan int), make(chan int, 1)
+	done := c.Start(ctx, in, out)
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if res := <-done; res.Reason != StopCancelled ||
+		!errors.Is(res.Err, context.Canceled) {
+		t.Fatalf("cancelled run returned %s", res)
+	}
+	if c.InPtr != 0 || c.IsHalted() {
+		t.Fatalf("cancelled run moved to %d", c.InPtr)
+	}
+
+	// the machine picks up where it was cancelled
+	done = c.Start(context.Background(), in, out)
+	in <- 7
+	if res := <-done; res.Err != nil {
+		t.Fatalf("resumed run: %s", res)
+	}
+	if v := <-out; v != 7 {
+		t.Errorf("output: %d expected 7", v)
+	}
+}
@@ -0,0 +1,176 @@
+package intcomputer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Coverage is a Tracer recording which addresses were executed as
+// instructions and which way conditional jumps went. Coverage of several
+// runs, or several computers, is combined by tracing them into the same
+// Coverage or by merging.
+type Coverage struct {
+	mu       sync.Mutex
+	Addrs    map[int]int          `json:"addrs"`
+	Branches map[int]*BranchCount `json:"branches"`
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{Addrs: map[int]int{}, Branches: map[int]*BranchCount{}}
+}
+
+func (cv *Coverage) Trace(s *StepInfo) {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	cv.Addrs[s.InPtr]++
+	countBranch(cv.Branches, s)
+}
+
+// Merge adds the coverage recorded in o
+func (cv *Coverage) Merge(o *Coverage) {
+	if o == cv {
+		return
+	}
+	// copy o first, holding both locks at once deadlocks merges both ways
+	o.mu.Lock()
+	addrs := make(map[int]int, len(o.Addrs))
+	for a, n := range o.Addrs {
+		addrs[a] = n
+	}
+	branches := make(map[int]BranchCount, len(o.Branches))
+	for a, ob := range o.Branches {
+		branches[a] = *ob
+	}
+	o.mu.Unlock()
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	for a, n := range addrs {
+		cv.Addrs[a] += n
+	}
+	for a, ob := range branches {
+		b := cv.Branches[a]
+		if b == nil {
+			b = &BranchCount{}
+			cv.Branches[a] = b
+		}
+		b.Taken += ob.Taken
+		b.NotTaken += ob.NotTaken
+	}
+}
+
+// Covered reports whether addr was executed as an instruction
+func (cv *Coverage) Covered(addr int) bool {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	return cv.Addrs[addr] > 0
+}
+
+func (cv *Coverage) WriteJSON(w io.Writer) error {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	return json.NewEncoder(w).Encode(cv)
+}
+
+// ReadCoverage reads coverage written by WriteJSON
+func ReadCoverage(r io.Reader) (*Coverage, error) {
+	cv := NewCoverage()
+	if err := json.NewDecoder(r).Decode(cv); err != nil {
+		return nil, err
+	}
+	return cv, nil
+}
+
+// CoverageStats summarises coverage of a program
+type CoverageStats struct {
+	// Instructions found by disassembling the program, and how many of
+	// them were executed
+	Instructions, Covered int
+	// Branches are the conditional jumps found, BothWays those seen both
+	// taken and not taken
+	Branches, BothWays int
+}
+
+func (s CoverageStats) String() string {
+	percent := func(n, d int) float64 {
+		if d == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(d)
+	}
+	return fmt.Sprintf("%d/%d instructions covered (%.1f%%), %d/%d branches both ways (%.1f%%)",
+		s.Covered, s.Instructions, percent(s.Covered, s.Instructions),
+		s.BothWays, s.Branches, percent(s.BothWays, s.Branches))
+}
+
+// Stats measures the coverage of the instructions of program
+func (cv *Coverage) Stats(program []int) CoverageStats {
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	s := CoverageStats{}
+	for _, l := range Disassemble(program) {
+		if l.Instruction == nil {
+			continue
+		}
+		s.Instructions++
+		if cv.Addrs[l.Addr] > 0 {
+			s.Covered++
+		}
+		switch l.Instruction.Opcode {
+		case JmpIfTrue, JmpIfFalse:
+			s.Branches++
+			if b := cv.Branches[l.Addr]; b != nil && b.Taken > 0 && b.NotTaken > 0 {
+				s.BothWays++
+			}
+		}
+	}
+	return s
+}
+
+// WriteListing writes the disassembly of program with every line marked
+// + when covered and - when not. Data lines holding addresses that were
+// executed, by jumping into the middle of an instruction or into code the
+// program wrote, are marked !. Conditional jumps are annotated with the
+// directions taken.
+func (cv *Coverage) WriteListing(w io.Writer, program []int) error {
+	stats := cv.Stats(program)
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	sb := &strings.Builder{}
+	for _, l := range Disassemble(program) {
+		mark := " "
+		if l.Instruction != nil {
+			mark = "-"
+			if cv.Addrs[l.Addr] > 0 {
+				mark = "+"
+			}
+		}
+		for a := l.Addr; a < l.Addr+l.Len(); a++ {
+			if cv.Addrs[a] > 0 && (l.Instruction == nil || a != l.Addr) {
+				mark = "!"
+			}
+		}
+
+		note := ""
+		if b := cv.Branches[l.Addr]; b != nil && mark == "+" {
+			switch {
+			case b.Taken > 0 && b.NotTaken > 0:
+				note = "both ways"
+			case b.Taken > 0:
+				note = "always taken"
+			default:
+				note = "never taken"
+			}
+			note = fmt.Sprintf("  <- %s (%d/%d)", note, b.Taken, b.NotTaken)
+		}
+		fmt.Fprintf(sb, "%s %s%s\n", mark, l, note)
+	}
+	fmt.Fprintf(sb, "\n%s\n", stats)
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,85 @@
+package intcomputer
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	// outputs 1 for a positive input and 0 otherwise
+	program := []int{3, 15, 1007, 15, 1, 16, 1005, 16, 12, 104, 1, 99, 104, 0, 99, 0, 0}
+	run := func(cv *Coverage, in int) {
+		t.Helper()
+		c := CreateIntComputer(program, CreateLogger(), nil, func(n int) {})
+		c.Tracer = cv
+		c.QueueInput(in)
+		if _, err := c.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pos, neg := NewCoverage(), NewCoverage()
+	run(pos, 5)
+	run(neg, -5)
+
+	if !pos.Covered(9) || pos.Covered(12) || !neg.Covered(12) || neg.Covered(9) {
+		t.Fatalf("covered: %v %v", pos.Addrs, neg.Addrs)
+	}
+	s := pos.Stats(program)
+	if s != (CoverageStats{Instructions: 7, Covered: 5, Branches: 1}) {
+		t.Errorf("stats: %s", s)
+	}
+
+	// merging in a copy read back from JSON
+	buf := &bytes.Buffer{}
+	if err := neg.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ReadCoverage(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos.Merge(saved)
+	s = pos.Stats(program)
+	if s != (CoverageStats{Instructions: 7, Covered: 7, Branches: 1, BothWays: 1}) {
+		t.Errorf("merged stats: %s", s)
+	}
+	if pos.Addrs[0] != 2 {
+		t.Errorf("address 0 executed %d times expected 2", pos.Addrs[0])
+	}
+
+	sb := &strings.Builder{}
+	if err := neg.WriteListing(sb, program); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []string{
+		"+ 0006: JumpIfTrue 16, #12",
+		"<- always taken (1/0)",
+		"- 0009: Output #1",
+		"+ 0012: Output #0",
+		"5/7 instructions covered",
+	} {
+		if !strings.Contains(sb.String(), l) {
+			t.Errorf("listing lacks %q:\n%s", l, sb)
+		}
+	}
+}
+
+func TestCoverage_MergeBothWays(t *testing.T) {
+	a, b := NewCoverage(), NewCoverage()
+	a.Addrs[0], b.Addrs[1] = 1, 1
+
+	wg := sync.WaitGroup{}
+	// counts double with every merge, keep them from overflowing
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() { defer wg.Done(); a.Merge(b) }()
+		go func() { defer wg.Done(); b.Merge(a) }()
+	}
+	wg.Wait()
+	if !a.Covered(1) || !b.Covered(0) {
+		t.Errorf("merged %v %v", a.Addrs, b.Addrs)
+	}
+}
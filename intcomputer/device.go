@@ -0,0 +1,146 @@
+package intcomputer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Device handles the addresses of a memory mapped region of Size words.
+// Offsets are relative to the start of the region. Devices mapped into
+// computers that run concurrently must do their own locking.
+type Device interface {
+	Size() int
+	Read(offset int) (int, error)
+	Write(offset, v int) error
+}
+
+// Mapping is a region of memory handled by a device
+type Mapping struct {
+	Start, Size int
+	Device      Device
+}
+
+func (r *Mapping) contains(ptr int) bool {
+	return ptr >= r.Start && ptr < r.Start+r.Size
+}
+
+// ErrDevice is an error returned by a mapped device
+type ErrDevice struct {
+	Fault
+	Addr  int
+	Write bool
+	Err   error
+}
+
+func (e *ErrDevice) Error() string {
+	op := "MEMREAD"
+	if e.Write {
+		op = "MEMWRITE"
+	}
+	return fmt.Sprintf("%s (addr = %d) Device error: %s %s", op, e.Addr, e.Err, &e.Fault)
+}
+
+func (e *ErrDevice) Unwrap() error {
+	return e.Err
+}
+
+// Map hands the words from start on to d. Reads and writes of them,
+// including instruction fetches, go to the device instead of memory until
+// the region is unmapped. Regions may not overlap.
+func (m *Memory) Map(start int, d Device) error {
+	if d == nil {
+		return fmt.Errorf("Mapping %d: no device", start)
+	}
+	size := d.Size()
+	if size <= 0 || start < 0 || start+size > m.MaxSize() {
+		return fmt.Errorf("Mapping %d+%d out of range", start, size)
+	}
+	i := sort.Search(len(m.mappings), func(i int) bool {
+		return m.mappings[i].Start+m.mappings[i].Size > start
+	})
+	if i < len(m.mappings) && m.mappings[i].Start < start+size {
+		return fmt.Errorf("Mapping %d+%d overlaps %d+%d", start, size,
+			m.mappings[i].Start, m.mappings[i].Size)
+	}
+	m.mappings = append(m.mappings, Mapping{})
+	copy(m.mappings[i+1:], m.mappings[i:])
+	m.mappings[i] = Mapping{Start: start, Size: size, Device: d}
+	m.remapped(start, size)
+	return nil
+}
+
+// Unmap removes the region starting at start, memory shows through again
+func (m *Memory) Unmap(start int) error {
+	for i, r := range m.mappings {
+		if r.Start == start {
+			m.mappings = append(m.mappings[:i], m.mappings[i+1:]...)
+			m.remapped(r.Start, r.Size)
+			return nil
+		}
+	}
+	return fmt.Errorf("No mapping at %d", start)
+}
+
+// Mappings lists the mapped regions in address order
+func (m *Memory) Mappings() []Mapping {
+	return append([]Mapping(nil), m.mappings...)
+}
+
+// mapping returns the region holding ptr, nil if memory handles it
+func (m *Memory) mapping(ptr int) *Mapping {
+	i := sort.Search(len(m.mappings), func(i int) bool {
+		return m.mappings[i].Start+m.mappings[i].Size > ptr
+	})
+	if i < len(m.mappings) && m.mappings[i].contains(ptr) {
+		return &m.mappings[i]
+	}
+	return nil
+}
+
+// peek reads ptr like readAddress but leaves mapped devices alone, ok is
+// false for their words
+func (m *Memory) peek(ptr int) (v int, ok bool, err error) {
+	if len(m.mappings) > 0 && m.mapping(ptr) != nil {
+		return 0, false, nil
+	}
+	v, err = m.readAddress(ptr)
+	return v, err == nil, err
+}
+
+// mapped reports whether any of the words from start up to end is mapped
+func (m *Memory) mapped(start, end int) bool {
+	for _, r := range m.mappings {
+		if r.Start < end && start < r.Start+r.Size {
+			return true
+		}
+	}
+	return false
+}
+
+// remapped drops what was decoded or compiled from the words of a region
+// that was mapped or unmapped
+func (m *Memory) remapped(start, size int) {
+	for a := start; a < start+size && a < len(m.decoded); a++ {
+		m.decoded[a] = nil
+	}
+	for a := start; a < start+size && a < len(m.code); a++ {
+		if m.code[a] > 0 {
+			m.codeWrites = append(m.codeWrites, a)
+		}
+	}
+}
+
+func (r *Mapping) read(ptr int) (int, error) {
+	v, err := r.Device.Read(ptr - r.Start)
+	if err != nil {
+		return -1, &ErrDevice{Addr: ptr, Err: err}
+	}
+	return v, nil
+}
+
+func (r *Mapping) write(v, ptr int) error {
+	if err := r.Device.Write(ptr-r.Start, v); err != nil {
+		return &ErrDevice{Addr: ptr, Write: true, Err: err}
+	}
+	return nil
+}
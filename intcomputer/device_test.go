@@ -0,0 +1,218 @@
+package intcomputer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rom is a device of read-only words
+type rom []int
+
+var errReadOnly = errors.New("read only")
+
+func (r rom) Size() int {
+	return len(r)
+}
+
+func (r rom) Read(off int) (int, error) {
+	return r[off], nil
+}
+
+func (r rom) Write(off, v int) error {
+	return errReadOnly
+}
+
+func TestMemory_Map(t *testing.T) {
+	m := &Memory{}
+	if err := m.Map(10, make(rom, 5)); err != nil {
+		t.Fatalf("Map: %s", err)
+	}
+	if err := m.Map(0, make(rom, 2)); err != nil {
+		t.Fatalf("Map: %s", err)
+	}
+	tt := []struct {
+		start int
+		d     Device
+	}{
+		{14, make(rom, 1)},
+		{5, make(rom, 6)},
+		{1, make(rom, 1)},
+		{20, rom{}},
+		{-1, make(rom, 1)},
+		{DefaultMaxMemory - 1, make(rom, 2)},
+		{20, nil},
+	}
+	for _, tc := range tt {
+		if err := m.Map(tc.start, tc.d); err == nil {
+			t.Errorf("Map(%d, %v) succeeded", tc.start, tc.d)
+		}
+	}
+	if err := m.Map(5, make(rom, 5)); err != nil {
+		t.Errorf("Map between regions: %s", err)
+	}
+
+	got := []int{}
+	for _, r := range m.Mappings() {
+		got = append(got, r.Start)
+	}
+	if !equalInts(got, []int{0, 5, 10}) {
+		t.Errorf("mappings at %v", got)
+	}
+	if err := m.Unmap(5); err != nil || len(m.Mappings()) != 2 || m.mapping(7) != nil {
+		t.Errorf("Unmap: %v %v", err, m.Mappings())
+	}
+	if err := m.Unmap(5); err == nil {
+		t.Errorf("Unmap twice succeeded")
+	}
+}
+
+func TestMemory_Devices(t *testing.T) {
+	out := &strings.Builder{}
+	con := NewConsole(strings.NewReader("x 42"), out)
+	fb := NewFramebuffer(3, 2)
+	now := time.Unix(0, 0)
+	clk := NewClock()
+	clk.Now = func() time.Time { return now }
+	clk.Write(0, 0)
+
+	program := []int{
+		// echo a character, then the number after it plus one
+		1001, 1000, 0, 1000, // 0: Add 1000, #0, 1000
+		1101, 10, 0, 1000, // 4: Add #10, #0, 1000
+		1001, 1001, 1, 1001, // 8: Add 1001, #1, 1001
+		// set two pixels
+		1101, 1, 0, 2001, // 12: Add #1, #0, 2001
+		1101, 1, 0, 2005, // 16: Add #1, #0, 2005
+		// output the clock
+		4, 3000, // 20: Output 3000
+		99,
+	}
+	outs := []int{}
+	c := CreateIntComputer(program, nil, nil, func(v int) { outs = append(outs, v) })
+	for _, d := range []struct {
+		start int
+		d     Device
+	}{{1000, con}, {2000, fb}, {3000, clk}} {
+		if err := c.Mem.Map(d.start, d.d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if res, err := c.Run(); err != nil || res.Reason != StopHalted {
+		t.Fatalf("result %s err %v", res, err)
+	}
+	if out.String() != "x\n43\n" {
+		t.Errorf("console %q", out.String())
+	}
+	if fb.String() != ".#.\n..#\n" || fb.Pixel(2, 1) != 1 {
+		t.Errorf("framebuffer\n%s", fb)
+	}
+	if !equalInts(outs, []int{1500}) {
+		t.Errorf("clock read %v", outs)
+	}
+
+	// devices do not touch memory behind them, unmapped it shows through
+	if v, _ := c.ReadMemory(2001, 1); v[0] != 1 {
+		t.Errorf("mapped read %d", v[0])
+	}
+	c.Mem.Unmap(2000)
+	if v, _ := c.ReadMemory(2001, 1); v[0] != 0 {
+		t.Errorf("unmapped read %d", v[0])
+	}
+
+	// end of input
+	for _, off := range []int{ConsoleChar, ConsoleNumber} {
+		if v, err := con.Read(off); v != -1 || err != nil {
+			t.Errorf("console read %d: %d %v", off, v, err)
+		}
+	}
+}
+
+func TestMemory_DeviceRandom(t *testing.T) {
+	a, b := NewRandom(7), NewRandom(7)
+	for i := 0; i < 3; i++ {
+		x, _ := a.Read(0)
+		y, _ := b.Read(0)
+		if x != y || x < 0 {
+			t.Errorf("same seed read %d and %d", x, y)
+		}
+	}
+	a.Write(0, 1)
+	b.Write(0, 1)
+	if x, _ := a.Read(0); x != func() int { y, _ := b.Read(0); return y }() {
+		t.Errorf("reseeded devices differ")
+	}
+	if _, err := a.Read(1); err == nil {
+		t.Errorf("read past the device succeeded")
+	}
+}
+
+func TestMemory_DeviceCode(t *testing.T) {
+	outs := []int{}
+	c := CreateIntComputer([]int{104, 1, 99}, nil, nil, func(v int) { outs = append(outs, v) })
+	c.Run()
+
+	// code fetched from a device, over the program and past it
+	code := rom{1105, 1, 100}
+	c.Mem.Map(0, code)
+	ext := rom{104, 2, 99}
+	c.Mem.Map(100, ext)
+	c.Reset()
+	c.Run()
+	ext[1] = 3
+	c.Reset()
+	c.Run()
+
+	c.Mem.Unmap(0)
+	c.Reset()
+	c.Run()
+	if !equalInts(outs, []int{1, 2, 3, 1}) {
+		t.Errorf("outputs %v", outs)
+	}
+
+	// device errors fault at the instruction
+	c.Program([]int{1101, 1, 1, 100, 99})
+	_, err := c.Run()
+	var de *ErrDevice
+	if !errors.As(err, &de) || !errors.Is(err, errReadOnly) || de.Addr != 100 || !de.Write ||
+		de.InPtr != 0 {
+		t.Errorf("err %v", err)
+	}
+
+	if cl := c.Clone(); len(cl.Mem.Mappings()) != 1 {
+		t.Errorf("clone mappings %v", cl.Mem.Mappings())
+	}
+}
+
+func TestMemory_DeviceWatchpoint(t *testing.T) {
+	for _, watch := range []bool{false, true} {
+		outs := []int{}
+		c := CreateIntComputer([]int{4, 1001, 4, 1001, 99}, nil, nil, func(v int) {
+			outs = append(outs, v)
+		})
+		con := NewConsole(strings.NewReader("1 2 3 4"), &strings.Builder{})
+		c.Mem.Map(1000, con)
+		if watch {
+			// looking ahead for it must not consume console input
+			c.AddWatchpoint(Watchpoint{From: 500, To: 500, Access: WatchReadWrite})
+		}
+		if res, err := c.Run(); err != nil || res.Reason != StopHalted {
+			t.Fatalf("result %s err %v", res, err)
+		}
+		if !equalInts(outs, []int{1, 2}) {
+			t.Errorf("watchpoint %v: outputs %v", watch, outs)
+		}
+	}
+
+	// reads of devices trigger regardless of Cond
+	c := CreateIntComputer([]int{4, 1000, 99}, nil, nil, func(int) {})
+	c.Mem.Map(1000, NewRandom(1))
+	c.AddWatchpoint(Watchpoint{From: 1000, To: 1000, Access: WatchRead,
+		Cond: func(addr, v int) bool { return false }})
+	if res, _ := c.Run(); res.Reason != StopBreakpoint || res.InPtr != 0 {
+		t.Errorf("result %s", res)
+	}
+}
@@ -0,0 +1,205 @@
+package intcomputer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+func errOffset(off int) error {
+	return fmt.Errorf("Offset %d out of range", off)
+}
+
+// Clock is a device of one word counting milliseconds. Writing sets the
+// count.
+type Clock struct {
+	mu    sync.Mutex
+	start time.Time
+	// Now is the time source, time.Now by default
+	Now func() time.Time
+}
+
+func NewClock() *Clock {
+	return &Clock{start: time.Now(), Now: time.Now}
+}
+
+func (c *Clock) Size() int {
+	return 1
+}
+
+func (c *Clock) Read(off int) (int, error) {
+	if off != 0 {
+		return -1, errOffset(off)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int(c.Now().Sub(c.start) / time.Millisecond), nil
+}
+
+func (c *Clock) Write(off, v int) error {
+	if off != 0 {
+		return errOffset(off)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.start = c.Now().Add(-time.Duration(v) * time.Millisecond)
+	return nil
+}
+
+// Random is a device of one word reading as a pseudo-random non-negative
+// number. Writing reseeds it.
+type Random struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func NewRandom(seed int64) *Random {
+	return &Random{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (r *Random) Size() int {
+	return 1
+}
+
+func (r *Random) Read(off int) (int, error) {
+	if off != 0 {
+		return -1, errOffset(off)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rnd.Int(), nil
+}
+
+func (r *Random) Write(off, v int) error {
+	if off != 0 {
+		return errOffset(off)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rnd.Seed(int64(v))
+	return nil
+}
+
+// Console is a device of two words. The first reads and writes
+// characters, the second reads whitespace separated numbers and writes
+// them one per line. Both read -1 at the end of input, input that is not
+// a number faults.
+type Console struct {
+	mu sync.Mutex
+	r  *bufio.Reader
+	w  io.Writer
+}
+
+// Console offsets
+const (
+	ConsoleChar = iota
+	ConsoleNumber
+)
+
+func NewConsole(r io.Reader, w io.Writer) *Console {
+	return &Console{r: bufio.NewReader(r), w: w}
+}
+
+func (c *Console) Size() int {
+	return 2
+}
+
+func (c *Console) Read(off int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch off {
+	case ConsoleChar:
+		b, err := c.r.ReadByte()
+		if err == io.EOF {
+			return -1, nil
+		}
+		return int(b), err
+	case ConsoleNumber:
+		var v int
+		if _, err := fmt.Fscan(c.r, &v); err == io.EOF {
+			return -1, nil
+		} else if err != nil {
+			return -1, err
+		}
+		return v, nil
+	}
+	return -1, errOffset(off)
+}
+
+func (c *Console) Write(off, v int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	switch off {
+	case ConsoleChar:
+		_, err = c.w.Write([]byte{byte(v)})
+	case ConsoleNumber:
+		_, err = fmt.Fprintln(c.w, v)
+	default:
+		err = errOffset(off)
+	}
+	return err
+}
+
+// Framebuffer is a device of Width*Height words, one per pixel, row by row
+type Framebuffer struct {
+	mu            sync.Mutex
+	Width, Height int
+	Pixels        []int
+}
+
+func NewFramebuffer(width, height int) *Framebuffer {
+	return &Framebuffer{Width: width, Height: height, Pixels: make([]int, width*height)}
+}
+
+func (f *Framebuffer) Size() int {
+	return len(f.Pixels)
+}
+
+func (f *Framebuffer) Read(off int) (int, error) {
+	if off < 0 || off >= len(f.Pixels) {
+		return -1, errOffset(off)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Pixels[off], nil
+}
+
+func (f *Framebuffer) Write(off, v int) error {
+	if off < 0 || off >= len(f.Pixels) {
+		return errOffset(off)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Pixels[off] = v
+	return nil
+}
+
+// Pixel returns the pixel at x, y
+func (f *Framebuffer) Pixel(x, y int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Pixels[y*f.Width+x]
+}
+
+// String draws the set pixels as # and the others as .
+func (f *Framebuffer) String() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sb := &strings.Builder{}
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			if f.Pixels[y*f.Width+x] != 0 {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,180 @@
+package intcomputer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// data words are listed this many to a line
+const dataWordsPerLine = 8
+
+type Operand struct {
+	Mode int
+	// Value is the raw parameter word
+	Value int
+	// Write is set for parameters giving the address to store results to
+	Write bool
+	// Resolved is what a Position read refers to in the program image, only
+	// meaningful if Static is set
+	Resolved int
+	Static   bool
+}
+
+func (o Operand) String() string {
+	switch o.Mode {
+	case Immediate:
+		return fmt.Sprintf("#%d", o.Value)
+	case Relative:
+		return fmt.Sprintf("@%d", o.Value)
+	}
+	return fmt.Sprintf("%d", o.Value)
+}
+
+// Line is a disassembled instruction, or a run of data words when
+// Instruction is nil
+type Line struct {
+	Addr        int
+	Words       []int
+	Instruction *Instruction
+	Operands    []Operand
+}
+
+// Len is the number of words the line covers
+func (l *Line) Len() int {
+	return len(l.Words)
+}
+
+// String formats the line in the syntax understood by the assembler,
+// prefixed with its address and followed by resolved values as comment
+func (l *Line) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%04d: ", l.Addr))
+	if l.Instruction == nil {
+		sb.WriteString(".data ")
+		for i, w := range l.Words {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(fmt.Sprintf("%d", w))
+		}
+		return sb.String()
+	}
+
+	sb.WriteString(OpcodeName(l.Instruction.Opcode))
+	comments := []string{}
+	for i, o := range l.Operands {
+		if i == 0 {
+			sb.WriteString(" ")
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(o.String())
+		if o.Static {
+			comments = append(comments, fmt.Sprintf("[%d]=%d", o.Value, o.Resolved))
+		}
+	}
+	if len(comments) > 0 {
+		return fmt.Sprintf("%-36s ; %s", sb.String(), strings.Join(comments, " "))
+	}
+	return sb.String()
+}
+
+// decodeAt decodes the instruction at addr of program, nil if the words
+// there do not form a well formed instruction
+func decodeAt(program []int, addr int) *Instruction {
+	code := program[addr]
+	if code < 0 {
+		return nil
+	}
+	ins := decode(code)
+	n := ParamCount(ins.Opcode)
+	if n < 0 || addr+n >= len(program) {
+		return nil
+	}
+	// no mode digits past the last parameter
+	for i := 0; i < n+2; i++ {
+		code /= 10
+	}
+	if code != 0 {
+		return nil
+	}
+	for i, m := range ins.ParamAddrModes {
+		if m != Position && m != Immediate && m != Relative {
+			return nil
+		}
+		if m == Immediate && isWriteParam(ins.Opcode, i) {
+			return nil
+		}
+	}
+	return ins
+}
+
+// Disassemble sweeps program from start to end. Words that do not decode
+// to a well formed instruction are grouped into data lines.
+func Disassemble(program []int) []*Line {
+	ret := []*Line{}
+	var data *Line
+	for addr := 0; addr < len(program); {
+		ins := decodeAt(program, addr)
+		if ins == nil {
+			if data == nil || data.Len() >= dataWordsPerLine {
+				data = &Line{Addr: addr}
+				ret = append(ret, data)
+			}
+			data.Words = append(data.Words, program[addr])
+			addr++
+			continue
+		}
+		data = nil
+
+		l := instructionLine(program, addr, ins)
+		ret = append(ret, l)
+		addr += l.Len()
+	}
+	return ret
+}
+
+// DisassembleAt decodes the instruction at addr of program, nil if the
+// words there do not form a well formed instruction
+func DisassembleAt(program []int, addr int) *Line {
+	if addr < 0 || addr >= len(program) {
+		return nil
+	}
+	ins := decodeAt(program, addr)
+	if ins == nil {
+		return nil
+	}
+	return instructionLine(program, addr, ins)
+}
+
+// instructionLine builds the line for ins decoded at addr of program
+func instructionLine(program []int, addr int, ins *Instruction) *Line {
+	n := len(ins.ParamAddrModes)
+	l := &Line{
+		Addr:        addr,
+		Words:       program[addr : addr+n+1],
+		Instruction: ins,
+		Operands:    make([]Operand, n),
+	}
+	for i, m := range ins.ParamAddrModes {
+		o := Operand{Mode: m, Value: program[addr+i+1],
+			Write: isWriteParam(ins.Opcode, i)}
+		if m == Position && !o.Write && o.Value >= 0 && o.Value < len(program) {
+			o.Resolved, o.Static = program[o.Value], true
+		}
+		l.Operands[i] = o
+	}
+	return l
+}
+
+// WriteListing writes the disassembly of program to w, one line per
+// instruction
+func WriteListing(w io.Writer, program []int) error {
+	for _, l := range Disassemble(program) {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package intcomputer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	program := []int{3, 9, 8, 9, 10, 9, 4, 9, 99, -1, 0, 1105, 1, 0, 10101, 1, 2, 3}
+
+	expected := []struct {
+		addr  int
+		text  string
+		isIns bool
+	}{
+		{addr: 0, text: "0000: Input 9", isIns: true},
+		{addr: 2, text: "0002: Equals 9, 10, 9", isIns: true},
+		{addr: 6, text: "0006: Output 9", isIns: true},
+		{addr: 8, text: "0008: Halt", isIns: true},
+		{addr: 9, text: "0009: .data -1, 0"},
+		{addr: 11, text: "0011: JumpIfTrue #1, #0", isIns: true},
+		// immediate write parameter does not decode cleanly, the rest runs
+		// past the end of the program
+		{addr: 14, text: "0014: .data 10101, 1, 2, 3"},
+	}
+
+	lines := Disassemble(program)
+	if len(lines) != len(expected) {
+		t.Fatalf("disassembled %d lines expected %d: %v", len(lines), len(expected), lines)
+	}
+	for i, e := range expected {
+		l := lines[i]
+		if l.Addr != e.addr || !strings.HasPrefix(l.String(), e.text) ||
+			(l.Instruction != nil) != e.isIns {
+			t.Errorf("line %d: %q expected %q", i, l, e.text)
+		}
+	}
+
+	// position reads are resolved against the program image
+	if s := lines[1].String(); !strings.HasSuffix(s, "; [9]=-1 [10]=0") {
+		t.Errorf("line %q is missing resolved operands", s)
+	}
+	if o := lines[1].Operands[2]; !o.Write || o.Static {
+		t.Errorf("write operand %+v", o)
+	}
+}
+
+func TestParseProgram(t *testing.T) {
+	p, err := ParseProgram(strings.NewReader("1,0, 0,3\n99,\n\n-4\n"))
+	if err != nil {
+		t.Fatalf("ParseProgram: %s", err)
+	}
+	expected := []int{1, 0, 0, 3, 99, -4}
+	if len(p) != len(expected) {
+		t.Fatalf("program: %v expected %v", p, expected)
+	}
+	for i := range p {
+		if p[i] != expected[i] {
+			t.Fatalf("program: %v expected %v", p, expected)
+		}
+	}
+
+	if _, err := ParseProgram(strings.NewReader("1,x,3")); err == nil {
+		t.Errorf("ParseProgram accepted an invalid word")
+	}
+}
@@ -0,0 +1,66 @@
+package intcomputer
+
+import "fmt"
+
+// Fault locates the instruction that raised an error. It is embedded in
+// every error returned by Run and Resume that is caused by the program.
+type Fault struct {
+	InPtr       int
+	Code        int
+	Instruction *Instruction
+}
+
+func (f *Fault) fault() *Fault {
+	return f
+}
+
+func (f *Fault) String() string {
+	if f.Instruction == nil {
+		return fmt.Sprintf("{InsPtr: %d}", f.InPtr)
+	}
+	return fmt.Sprintf("{InsPtr: %d Code: %d} %v", f.InPtr, f.Code, f.Instruction)
+}
+
+type faulter interface {
+	fault() *Fault
+}
+
+// locate fills in the Fault of err, if it has one, with the instruction
+// currently being executed
+func (c *IntComputer) locate(err error, code int, ins *Instruction) error {
+	if f, ok := err.(faulter); ok {
+		*f.fault() = Fault{InPtr: c.InPtr, Code: code, Instruction: ins}
+	}
+	return err
+}
+
+type ErrAddressOutOfRange struct {
+	Fault
+	Addr  int
+	Write bool
+}
+
+func (e *ErrAddressOutOfRange) Error() string {
+	op := "MEMREAD"
+	if e.Write {
+		op = "MEMWRITE"
+	}
+	return fmt.Sprintf("%s (addr = %d) Out of range %s", op, e.Addr, &e.Fault)
+}
+
+type ErrUnsupportedOpcode struct {
+	Fault
+}
+
+func (e *ErrUnsupportedOpcode) Error() string {
+	return fmt.Sprintf("Unsupported opcode %d %s", e.Code%100, &e.Fault)
+}
+
+type ErrUnsupportedAddrMode struct {
+	Fault
+	Mode int
+}
+
+func (e *ErrUnsupportedAddrMode) Error() string {
+	return fmt.Sprintf("Unsupported addressing mode %d %s", e.Mode, &e.Fault)
+}
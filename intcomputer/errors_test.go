@@ -0,0 +1,81 @@
+package intcomputer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIntComputer_Faults(t *testing.T) {
+	tt := []struct {
+		name         string
+		instructions []int
+		inPtr, code  int
+		check        func(err error) bool
+	}{
+		{
+			name:         "write out of range",
+			instructions: []int{1101, 1, 2, 5, 1101, 3, 4, 64, 99},
+			inPtr:        4,
+			code:         1101,
+			check: func(err error) bool {
+				var e *ErrAddressOutOfRange
+				return errors.As(err, &e) && e.Write && e.Addr == 64
+			},
+		},
+		{
+			name:         "read out of range",
+			instructions: []int{4, -1, 99},
+			inPtr:        0,
+			code:         4,
+			check: func(err error) bool {
+				var e *ErrAddressOutOfRange
+				return errors.As(err, &e) && !e.Write && e.Addr == -1
+			},
+		},
+		{
+			name:         "unsupported opcode",
+			instructions: []int{1101, 1, 2, 5, 42, 0},
+			inPtr:        4,
+			code:         42,
+			check: func(err error) bool {
+				var e *ErrUnsupportedOpcode
+				return errors.As(err, &e)
+			},
+		},
+		{
+			name:         "unsupported addressing mode",
+			instructions: []int{304, 0, 99},
+			inPtr:        0,
+			code:         304,
+			check: func(err error) bool {
+				var e *ErrUnsupportedAddrMode
+				return errors.As(err, &e) && e.Mode == 3
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		c := CreateIntComputer(tc.instructions, CreateLogger(), nil, nil)
+		c.Mem.SetMaxSize(64)
+
+		_, err := c.Run()
+		if err == nil || !tc.check(err) {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+
+		var f faulter
+		if !errors.As(err, &f) {
+			t.Errorf("%s: %v does not carry a fault", tc.name, err)
+			continue
+		}
+		if fault := f.fault(); fault.InPtr != tc.inPtr || fault.Code != tc.code ||
+			fault.Instruction == nil {
+			t.Errorf("%s: fault %s expected {InsPtr: %d Code: %d}",
+				tc.name, fault, tc.inPtr, tc.code)
+		}
+		if c.InPtr != tc.inPtr {
+			t.Errorf("%s: InPtr moved to %d after fault", tc.name, c.InPtr)
+		}
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/som.subhojit1988/aoc_2k19/intcomputer
 
-go 1.15
+go 1.21
@@ -10,44 +10,50 @@ type Instruction struct {
 	ParamAddrModes []int
 }
 
-func (i *Instruction) String() string {
+// OpcodeName is the mnemonic of op, "" if op is not supported
+func OpcodeName(op int) string {
+	if o := lookup(op); o != nil {
+		return o.Name
+	}
+	return ""
+}
 
-	opToString := func(op int) string {
-		var ret string
-		switch op {
-		case Add:
-			ret = "Add"
-		case Mul:
-			ret = "Mul"
-		case Input:
-			ret = "Input"
-		case Output:
-			ret = "Output"
-		case JmpIfTrue:
-			ret = "JumpIfTrue"
-		case JmpIfFalse:
-			ret = "JumpIfFalse"
-		case LessThan:
-			ret = "LessThan"
-		case Equals:
-			ret = "Equals"
-		case Halt:
-			ret = "Halt"
-		default:
-			ret = "Unsupported instruction"
+// LookupOpcode finds the opcode of a mnemonic, ignoring case
+func LookupOpcode(name string) (int, bool) {
+	for _, o := range opcodes {
+		if o != nil && strings.EqualFold(o.Name, name) {
+			return o.Code, true
 		}
-		return ret
 	}
+	return Unsupported, false
+}
 
-	addrModeToString := func(m int) string {
-		var ret string
-		switch m {
-		case Position:
-			ret = "Position"
-		case Immediate:
-			ret = "Immediate"
-		}
-		return ret
+// ParamCount is the number of parameters op takes, -1 if op is not
+// supported
+func ParamCount(op int) int {
+	if o := lookup(op); o != nil {
+		return o.Params
+	}
+	return -1
+}
+
+func addrModeToString(m int) string {
+	var ret string
+	switch m {
+	case Position:
+		ret = "Position"
+	case Immediate:
+		ret = "Immediate"
+	case Relative:
+		ret = "Relative"
+	}
+	return ret
+}
+
+func (i *Instruction) String() string {
+	op := OpcodeName(i.Opcode)
+	if op == "" {
+		op = "Unsupported instruction"
 	}
 
 	strB := strings.Builder{}
@@ -57,46 +63,29 @@ func (i *Instruction) String() string {
 	}
 
 	return fmt.Sprintf("Opcode= %d {%s} AddressingModes [p1, p2, ...] = %s",
-		i.Opcode, opToString(i.Opcode), strB.String())
+		i.Opcode, op, strB.String())
 }
 
-func decode(ins int) *Instruction {
-	// return opcode, {param-1-addrMode, parma-2-addrMode, ...}
-	parse := func(in int) (int, []int) {
-		op := in % 100
-		in /= 100
+// isWriteParam reports whether the i-th parameter of op gives the address
+// to store results to rather than a value to be read.
+func isWriteParam(op, i int) bool {
+	o := lookup(op)
+	return o != nil && i >= 0 && i < 64 && o.writes&(1<<uint(i)) != 0
+}
 
-		var ret []int
-		switch op {
-		case Add, Mul, LessThan, Equals:
-			// 3 parameters
-			ret = make([]int, 3)
-			ret[0] = in % 10
-			in /= 10
-			ret[1] = in % 10
-			in /= 10
-			ret[2] = Immediate // param value gives address to store results to
-		case Input, Output:
-			// 1 parameter
-			ret = make([]int, 1)
-			if op == Input {
-				ret[0] = Immediate
-			} else {
-				ret[0] = in % 10
-			}
-		case JmpIfTrue, JmpIfFalse:
-			// 2 parameters
-			ret = make([]int, 2)
-			ret[0] = in % 10
-			in /= 10
-			ret[1] = in % 10
-		default:
-			// 0 params ex: Halt
-			ret = make([]int, 0)
-		}
-		return op, ret
+func decode(ins int) *Instruction {
+	// opcode, {param-1-addrMode, parma-2-addrMode, ...}
+	op, in := ins%100, ins/100
+	n := ParamCount(op)
+	if n < 0 {
+		// unsupported, no params
+		n = 0
+	}
+	addrModes := make([]int, n)
+	for i := range addrModes {
+		addrModes[i] = in % 10
+		in /= 10
 	}
-	op, addrModes := parse(ins)
 	return &Instruction{
 		Opcode:         op,
 		ParamAddrModes: addrModes,
@@ -8,11 +8,18 @@ func TestInstructionDecode(t *testing.T) {
 		ins  *Instruction
 	}{
 		{code: 10101, ins: &Instruction{Opcode: 01, ParamAddrModes: []int{1, 0, 1}}},
+		{code: 21202, ins: &Instruction{Opcode: 02, ParamAddrModes: []int{2, 1, 2}}},
+		{code: 203, ins: &Instruction{Opcode: 03, ParamAddrModes: []int{2}}},
+		{code: 109, ins: &Instruction{Opcode: 9, ParamAddrModes: []int{1}}},
 	}
 
 	for _, tc := range tt {
 		ins := decode(tc.code)
 		e := tc.ins
+		if len(ins.ParamAddrModes) != len(e.ParamAddrModes) {
+			t.Errorf("FAIL: Parameter count mismatch expected= %s actual= %s", e, ins)
+			continue
+		}
 		if ins.Opcode != e.Opcode {
 			t.Errorf("FAIL: Opcode mismatch expected= %s actual= %s", e, ins)
 		}
@@ -17,7 +17,7 @@ func TestIntComputer_ExecuteEqualPosition(t *testing.T) {
 		}
 	})
 
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 
@@ -31,7 +31,7 @@ func TestIntComputer_ExecuteEqualPosition(t *testing.T) {
 		}
 	}
 
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 
@@ -52,7 +52,7 @@ func TestIntComputer_ExecuteEqualImmediate(t *testing.T) {
 		}
 	})
 
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 
@@ -66,7 +66,7 @@ func TestIntComputer_ExecuteEqualImmediate(t *testing.T) {
 	}
 
 	c.Program(instructions)
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 }
@@ -86,7 +86,7 @@ func TestIntComputer_ExecuteLtPosition(t *testing.T) {
 		}
 	})
 
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 
@@ -100,7 +100,7 @@ func TestIntComputer_ExecuteLtPosition(t *testing.T) {
 		}
 	}
 
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 }
@@ -120,7 +120,7 @@ func TestIntComputer_ExecuteLtImmediate(t *testing.T) {
 		}
 	})
 
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 
@@ -133,7 +133,7 @@ func TestIntComputer_ExecuteLtImmediate(t *testing.T) {
 		}
 	}
 	c.Program(instructions)
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 }
@@ -155,7 +155,7 @@ func TestIntComputer_JmpPosition(t *testing.T) {
 		}
 	})
 
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 
@@ -168,7 +168,7 @@ func TestIntComputer_JmpPosition(t *testing.T) {
 		}
 	}
 
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 }
@@ -192,7 +192,7 @@ func TestIntComputer_JmpImmediate(t *testing.T) {
 		}
 	})
 
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 
@@ -205,7 +205,7 @@ func TestIntComputer_JmpImmediate(t *testing.T) {
 		}
 	}
 	c.Program(instructions)
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 }
@@ -233,21 +233,137 @@ func TestIntComputer_ExecuteJmp(t *testing.T) {
 	})
 
 	input, expected = 7, 999
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 
 	c.Reset()
 	c.Program(instructions)
 	input, expected = 8, 1000
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 
 	c.Reset()
 	c.Program(instructions)
 	input, expected = 9, 1001
-	if err := c.Run(); err != nil {
+	if _, err := c.Run(); err != nil {
 		panic(err)
 	}
 }
+
+func TestIntComputer_Relative(t *testing.T) {
+
+	// set relative base to 12, read an input into 13, double it into 14
+	// and output it, all through relative addressing
+	instructions := []int{109, 12, 203, 1, 22201, 1, 1, 2, 204, 2, 99,
+		0, 0, 0, 0}
+	l := CreateLogger()
+
+	input, expected := 21, 42
+	c := CreateIntComputer(instructions, l, func() int {
+		return input
+	}, func(n int) {
+		t.Logf("Output: %d", n)
+		if n != expected {
+			t.Errorf("result: %d, expected %d", n, expected)
+		}
+	})
+
+	if _, err := c.Run(); err != nil {
+		panic(err)
+	}
+
+	if c.RelBase != 12 {
+		t.Errorf("relative base: %d, expected 12", c.RelBase)
+	}
+}
+
+func TestIntComputer_SetRegisters(t *testing.T) {
+	// a relative base and instruction pointer set before running are used
+	// from the first instruction on
+	var out []int
+	c := CreateIntComputer([]int{204, 0, 99, 0, 0, 77}, CreateLogger(), nil, func(n int) {
+		out = append(out, n)
+	})
+	c.RelBase = 5
+	if _, err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Program([]int{104, 1, 99, 104, 2, 99})
+	c.InPtr = 3
+	if _, err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !equalInts(out, []int{77, 2}) || c.InPtr != 5 {
+		t.Errorf("output %v InPtr %d", out, c.InPtr)
+	}
+}
+
+func TestIntComputer_Quine(t *testing.T) {
+
+	// takes no input and produces a copy of itself as output, uses
+	// memory beyond the program
+	instructions := []int{109, 1, 204, -1, 1001, 100, 1, 100, 1008, 100, 16,
+		101, 1006, 101, 0, 99}
+	l := CreateLogger()
+
+	out := []int{}
+	c := CreateIntComputer(instructions, l, nil, func(n int) {
+		out = append(out, n)
+	})
+
+	if _, err := c.Run(); err != nil {
+		panic(err)
+	}
+
+	if len(out) != len(instructions) {
+		t.Fatalf("output: %v, expected %v", out, instructions)
+	}
+	for i := range out {
+		if out[i] != instructions[i] {
+			t.Fatalf("output: %v, expected %v", out, instructions)
+		}
+	}
+}
+
+func TestIntComputer_Reset(t *testing.T) {
+
+	// store the input over the program's first word and output it
+	instructions := []int{3, 0, 4, 0, 99}
+	out := []int{}
+	c := CreateIntComputer(instructions, CreateLogger(), nil, func(n int) {
+		out = append(out, n)
+	})
+
+	for _, in := range []int{5, 6} {
+		c.Reset()
+		c.QueueInput(in)
+		if res, err := c.Run(); err != nil || res.Reason != StopHalted {
+			t.Fatalf("result: %s %v", res, err)
+		}
+	}
+	if len(out) != 2 || out[0] != 5 || out[1] != 6 {
+		t.Errorf("output: %v expected [5 6]", out)
+	}
+
+	// pending input does not survive a Reset
+	c.QueueInput(7)
+	c.Reset()
+	if res, _ := c.Run(); res.Reason != StopAwaitingInput {
+		t.Errorf("result: %s expected to await input", res)
+	}
+
+	// a new program replaces the image
+	c.Program([]int{104, 8, 99})
+	c.Run()
+	c.Reset()
+	c.Run()
+	if len(out) != 4 || out[2] != 8 || out[3] != 8 {
+		t.Errorf("output: %v expected [5 6 8 8]", out)
+	}
+	if instructions[0] != 3 {
+		t.Errorf("Reset changed the caller's program")
+	}
+}
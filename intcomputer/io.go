@@ -0,0 +1,57 @@
+package intcomputer
+
+import "errors"
+
+// ErrNoInput is returned by ReadInput when no input is available yet
+var ErrNoInput = errors.New("No input available")
+
+// QueueInput queues vs to be read by Input instructions ahead of InFunc
+// or the input channel. It wakes up a computer awaiting input, which
+// continues on the next Run or Resume.
+func (c *IntComputer) QueueInput(vs ...int) {
+	c.inQueue = append(c.inQueue, vs...)
+	c.flags &= 0xfffb
+}
+
+// ReadInput takes the next input, for instruction handlers
+func (c *IntComputer) ReadInput() (int, error) {
+	v, err := c.nextInput()
+	if err == nil && c.rec != nil {
+		c.rec.Input = append(c.rec.Input, v)
+	}
+	return v, err
+}
+
+func (c *IntComputer) nextInput() (int, error) {
+	if len(c.inQueue) > 0 {
+		v := c.inQueue[0]
+		c.inQueue = c.inQueue[1:]
+		return v, nil
+	}
+	if c.source != nil {
+		return c.source()
+	}
+	if c.InFunc != nil {
+		return c.InFunc(), nil
+	}
+	return 0, ErrNoInput
+}
+
+// WriteOutput outputs v, for instruction handlers
+func (c *IntComputer) WriteOutput(v int) error {
+	if c.Limits.MaxOutputs > 0 && c.runOutputs >= c.Limits.MaxOutputs {
+		return &ErrLimitExceeded{Limit: LimitOutputs}
+	}
+	if c.sink != nil {
+		if err := c.sink(v); err != nil {
+			return err
+		}
+	} else {
+		c.OutFunc(v)
+	}
+	c.runOutputs++
+	if c.rec != nil {
+		c.rec.Output = append(c.rec.Output, v)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package intcomputer
+
+import "testing"
+
+func TestIntComputer_AwaitInput(t *testing.T) {
+
+	// add two inputs and output the sum
+	instructions := []int{3, 11, 3, 12, 1, 11, 12, 11, 4, 11, 99, 0, 0}
+	out := []int{}
+	c := CreateIntComputer(instructions, CreateLogger(), nil, func(n int) {
+		out = append(out, n)
+	})
+
+	c.QueueInput(3)
+	if _, err := c.Run(); err != nil {
+		panic(err)
+	}
+	if !c.IsAwaitingInput() || c.IsHalted() {
+		t.Fatalf("computer is not awaiting input")
+	}
+	if c.InPtr != 2 {
+		t.Fatalf("suspended at %d, expected 2", c.InPtr)
+	}
+
+	// nothing changes while still starved
+	if _, err := c.Resume(); err != nil {
+		panic(err)
+	}
+	if !c.IsAwaitingInput() || c.InPtr != 2 {
+		t.Fatalf("starved resume moved to %d", c.InPtr)
+	}
+
+	c.QueueInput(4)
+	if c.IsAwaitingInput() {
+		t.Errorf("queued input did not wake up the computer")
+	}
+	if _, err := c.Resume(); err != nil {
+		panic(err)
+	}
+	if !c.IsHalted() {
+		t.Fatalf("computer did not halt")
+	}
+	if len(out) != 1 || out[0] != 7 {
+		t.Errorf("output: %v, expected [7]", out)
+	}
+}
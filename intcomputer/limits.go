@@ -0,0 +1,60 @@
+package intcomputer
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limits bound a single Run, Resume or RunContext, zero values are
+// unlimited
+type Limits struct {
+	MaxSteps   int
+	MaxOutputs int
+	Timeout    time.Duration
+}
+
+// Min combines two sets of limits, keeping the tighter of each
+func (l Limits) Min(o Limits) Limits {
+	minOf := func(x, y int64) int64 {
+		if x == 0 || (y != 0 && y < x) {
+			return y
+		}
+		return x
+	}
+	return Limits{
+		MaxSteps:   int(minOf(int64(l.MaxSteps), int64(o.MaxSteps))),
+		MaxOutputs: int(minOf(int64(l.MaxOutputs), int64(o.MaxOutputs))),
+		Timeout:    time.Duration(minOf(int64(l.Timeout), int64(o.Timeout))),
+	}
+}
+
+type LimitKind int
+
+const (
+	LimitSteps LimitKind = iota + 1
+	LimitOutputs
+	LimitTime
+)
+
+func (k LimitKind) String() string {
+	switch k {
+	case LimitSteps:
+		return "MaxSteps"
+	case LimitOutputs:
+		return "MaxOutputs"
+	case LimitTime:
+		return "Timeout"
+	}
+	return fmt.Sprintf("LimitKind(%d)", int(k))
+}
+
+type ErrLimitExceeded struct {
+	Limit LimitKind
+}
+
+func (e *ErrLimitExceeded) Error() string {
+	return fmt.Sprintf("Limit exceeded: %s", e.Limit)
+}
+
+// how many instructions to execute between checks of the clock
+const timeCheckInterval = 1024
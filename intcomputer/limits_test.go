@@ -0,0 +1,96 @@
+package intcomputer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// outputs 1 forever
+var spin = []int{104, 1, 1105, 1, 0}
+
+func TestIntComputer_Limits(t *testing.T) {
+	tt := []struct {
+		limits  Limits
+		kind    LimitKind
+		steps   int
+		outputs int
+	}{
+		{limits: Limits{MaxSteps: 7}, kind: LimitSteps, steps: 7, outputs: 4},
+		{limits: Limits{MaxOutputs: 3}, kind: LimitOutputs, steps: 6, outputs: 3},
+		{limits: Limits{MaxSteps: 100, MaxOutputs: 3}, kind: LimitOutputs, steps: 6,
+			outputs: 3},
+		{limits: Limits{Timeout: 10 * time.Millisecond}, kind: LimitTime},
+	}
+
+	for _, tc := range tt {
+		c := CreateIntComputer(spin, nil, nil, func(int) {})
+		c.Limits = tc.limits
+		res, err := c.Run()
+		var le *ErrLimitExceeded
+		if !errors.As(err, &le) || le.Limit != tc.kind {
+			t.Fatalf("%+v: error %v expected %s exceeded", tc.limits, err, tc.kind)
+		}
+		if res.Reason != StopLimitExceeded {
+			t.Errorf("%+v: stopped with %s", tc.limits, res.Reason)
+		}
+		if tc.kind != LimitTime && (res.Steps != tc.steps || res.Outputs != tc.outputs) {
+			t.Errorf("%+v: result %s expected %d steps, %d outputs", tc.limits, res,
+				tc.steps, tc.outputs)
+		}
+
+		// limits apply per run
+		if tc.kind == LimitOutputs {
+			if res, _ := c.Run(); res.Outputs != tc.outputs {
+				t.Errorf("%+v: second run output %d", tc.limits, res.Outputs)
+			}
+		}
+	}
+}
+
+func TestIntComputer_LimitsContext(t *testing.T) {
+	c := CreateIntComputer(spin, nil, nil, nil)
+	c.Limits = Limits{Timeout: 10 * time.Millisecond}
+
+	// nobody reads the output, the timeout has to interrupt the send
+	res, err := c.RunContext(context.Background(), nil, make(chan int))
+	var le *ErrLimitExceeded
+	if !errors.As(err, &le) || le.Limit != LimitTime || res.Reason != StopLimitExceeded {
+		t.Fatalf("result %s expected timeout", res)
+	}
+
+	// however soon it fires after the run started
+	c.Limits.Timeout = time.Microsecond
+	for i := 0; i < 100; i++ {
+		c.Reset()
+		if res, _ := c.RunContext(context.Background(), nil, make(chan int)); res.Reason != StopLimitExceeded {
+			t.Fatalf("run %d: result %s expected timeout", i, res)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.Reset()
+	if res, _ := c.RunContext(ctx, nil, make(chan int)); res.Reason != StopCancelled {
+		t.Errorf("result %s expected cancellation", res)
+	}
+
+	// a deadline of the caller is a cancellation, not the time limit
+	c.Limits.Timeout = time.Hour
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	c.Reset()
+	if res, err := c.RunContext(ctx, nil, make(chan int)); res.Reason != StopCancelled ||
+		!errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("result %s expected cancellation", res)
+	}
+}
+
+func TestLimits_Min(t *testing.T) {
+	l := Limits{MaxSteps: 10, Timeout: time.Second}.Min(
+		Limits{MaxSteps: 20, MaxOutputs: 5, Timeout: time.Millisecond})
+	if l != (Limits{MaxSteps: 10, MaxOutputs: 5, Timeout: time.Millisecond}) {
+		t.Errorf("Min: %+v", l)
+	}
+}
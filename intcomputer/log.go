@@ -0,0 +1,85 @@
+package intcomputer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Level is slog's level, so levels pass straight through to slog
+type Level = slog.Level
+
+const (
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
+)
+
+// Logger receives diagnostics. args are alternating keys and values, as
+// with slog.
+type Logger interface {
+	Log(level Level, msg string, args ...any)
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// SlogLogger routes diagnostics to l
+func SlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{l: l}
+}
+
+func (s *slogLogger) Log(level Level, msg string, args ...any) {
+	s.l.Log(context.Background(), level, msg, args...)
+}
+
+// BufferLogger keeps diagnostics of at least MinLevel in memory. It is
+// safe for use by computers running in different goroutines.
+type BufferLogger struct {
+	MinLevel Level
+
+	mu     sync.Mutex
+	buffer []string
+}
+
+// CreateLogger returns a BufferLogger keeping everything
+func CreateLogger() *BufferLogger {
+	return &BufferLogger{MinLevel: LevelDebug, buffer: []string{}}
+}
+
+func (l *BufferLogger) Log(level Level, msg string, args ...any) {
+	if level < l.MinLevel {
+		return
+	}
+	sb := &strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s %s", level, msg))
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			sb.WriteString(fmt.Sprintf(" %v=%v", args[i], args[i+1]))
+		} else {
+			sb.WriteString(fmt.Sprintf(" %v", args[i]))
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buffer = append(l.buffer, sb.String())
+}
+
+func (l *BufferLogger) Logs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ret := make([]string, len(l.buffer))
+	copy(ret, l.buffer)
+	return ret
+}
+
+func (l *BufferLogger) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buffer = []string{}
+}
@@ -0,0 +1,51 @@
+package intcomputer
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestBufferLogger(t *testing.T) {
+	l := CreateLogger()
+	l.MinLevel = LevelInfo
+	l.Log(LevelDebug, "dropped")
+	l.Log(LevelWarn, "kept", "addr", 4, "odd")
+
+	logs := l.Logs()
+	if len(logs) != 1 || logs[0] != "WARN kept addr=4 odd" {
+		t.Errorf("logs: %q", logs)
+	}
+	l.Clear()
+	if len(l.Logs()) != 0 {
+		t.Errorf("Clear kept %q", l.Logs())
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	b := &bytes.Buffer{}
+	h := slog.NewTextHandler(b, &slog.HandlerOptions{Level: slog.LevelInfo})
+	c := CreateIntComputer([]int{1101, 1, 1, 5, 42, 0}, SlogLogger(slog.New(h)),
+		nil, nil)
+
+	if _, err := c.Run(); err == nil {
+		t.Fatalf("unsupported opcode did not fail")
+	}
+	// the fault is logged as an error, memory growth is debug only
+	c.Store(1, 1000)
+	s := b.String()
+	if !strings.Contains(s, "level=ERROR") || !strings.Contains(s, "Stopped") ||
+		strings.Contains(s, "Growing") {
+		t.Errorf("slog output: %q", s)
+	}
+
+	// the buffer survives a Reset
+	l := CreateLogger()
+	c = CreateIntComputer([]int{99}, l, nil, nil)
+	c.Run()
+	c.Reset()
+	if len(l.Logs()) != 1 {
+		t.Errorf("logs: %q", l.Logs())
+	}
+}
@@ -2,19 +2,78 @@ package intcomputer
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
+const (
+	// DefaultMaxMemory is the number of addressable words when no other
+	// limit has been set with SetMaxSize
+	DefaultMaxMemory = 1 << 24
+
+	// writes up to a page past the dense storage grow it, writes further
+	// out land in the sparse map
+	pageSize = 4096
+)
+
+// Memory is zero-initialised and unbounded up to its maximum size. The
+// program image and anything written close to it live in a dense slice,
+// far away addresses are kept in a sparse map.
 type Memory struct {
 	storage []int
-	memPtr  int
-	logger  *Logger
+	sparse  map[int]int
+	maxSize int
+	logger  Logger
+
+	// decoded instruction words of the dense storage, by address. Only
+	// the instruction word is decoded, parameters are read afresh on
+	// every execution, so writes to it are all that invalidate an entry.
+	decoded []*Instruction
+	// decodings by instruction word, kept when the program is reloaded
+	interned map[int]*Instruction
+	noCache  bool
+
+	// gen counts program loads
+	gen int
+	// code counts the compiled blocks covering each address, writes to
+	// covered addresses are noted in codeWrites
+	code       []int32
+	codeWrites []int
+
+	// regions handled by devices, in address order
+	mappings []Mapping
 }
 
+// Size is the number of words in the dense backing store
 func (m *Memory) Size() int {
 	return len(m.storage)
 }
 
+// MaxSize is the number of addressable words
+func (m *Memory) MaxSize() int {
+	if m.maxSize <= 0 {
+		return DefaultMaxMemory
+	}
+	return m.maxSize
+}
+
+// SetMaxSize limits the addressable words to n, n <= 0 restores the
+// default
+func (m *Memory) SetMaxSize(n int) {
+	m.maxSize = n
+}
+
+// load replaces the memory contents with storage, keeping the size limit
+// and mapped devices
+func (m *Memory) load(storage []int) {
+	m.storage = storage
+	m.gen++
+	m.sparse = nil
+	for i := range m.decoded {
+		m.decoded[i] = nil
+	}
+}
+
 func (m *Memory) String() string {
 	dump := func() string {
 		sb := &strings.Builder{}
@@ -27,47 +86,135 @@ func (m *Memory) String() string {
 				sb.WriteString("\n")
 			}
 		}
+		if len(m.sparse) > 0 {
+			addrs := make([]int, 0, len(m.sparse))
+			for a := range m.sparse {
+				addrs = append(addrs, a)
+			}
+			sort.Ints(addrs)
+			sb.WriteString("\n... ")
+			for _, a := range addrs {
+				sb.WriteString(fmt.Sprintf("%d (%d) ", m.sparse[a], a))
+			}
+		}
 		return fmt.Sprintf("[ %s ]", sb.String())
 	}
-	return fmt.Sprintf("MEM: ptr= %d\n%s\n", m.memPtr, dump())
+	return fmt.Sprintf("MEM:\n%s\n", dump())
 }
 
-func (m *Memory) read(addrMode, d int) (int, error) {
-	var ret int
-	if m.memPtr+d >= m.Size() {
-		return -1, fmt.Errorf("MEMREAD (addressing-mode= %d, addr = %d) Out of range",
-			addrMode, m.memPtr+d)
+// paramAddress resolves the parameter word at ptr to the address it
+// refers to, as needed by parameters that are written to. Relative
+// addresses are taken from relBase.
+func (m *Memory) paramAddress(addrMode, ptr, relBase int) (int, error) {
+	x, err := m.readAddress(ptr)
+	if err != nil {
+		return -1, err
 	}
-	x := m.storage[m.memPtr+d]
 	switch addrMode {
-	case Position:
-		if x >= m.Size() {
-			return -1, fmt.Errorf("MEMREAD (addressing-mode= %d, addr = %d) Out of range",
-				addrMode, x)
-		}
-		ret = m.storage[x]
-	case Immediate:
-		ret = x
+	case Position, Immediate:
+		// write parameters are never immediate, treat as position
+		return x, nil
+	case Relative:
+		return relBase + x, nil
 	}
-	return ret, nil
+	return -1, &ErrUnsupportedAddrMode{Mode: addrMode}
 }
 
 func (m *Memory) readAddress(ptr int) (int, error) {
-	if ptr < 0 || ptr >= m.Size() {
-		return -1, fmt.Errorf("MEMREAD (addr = %d) Out of range", ptr)
+	if ptr < 0 || ptr >= m.MaxSize() {
+		return -1, &ErrAddressOutOfRange{Addr: ptr}
+	}
+	if len(m.mappings) > 0 {
+		if r := m.mapping(ptr); r != nil {
+			return r.read(ptr)
+		}
 	}
-	return m.storage[ptr], nil
+	if ptr < m.Size() {
+		return m.storage[ptr], nil
+	}
+	return m.sparse[ptr], nil
 }
 
 func (m *Memory) write(v, ptr int) error {
-	if ptr < 0 || ptr >= m.Size() {
-		return fmt.Errorf("MEMWRITE (addr = %d  v= %d) Out of range",
-			ptr, v)
+	if ptr < 0 || ptr >= m.MaxSize() {
+		return &ErrAddressOutOfRange{Addr: ptr, Write: true}
+	}
+	if len(m.mappings) > 0 {
+		if r := m.mapping(ptr); r != nil {
+			return r.write(v, ptr)
+		}
+	}
+	if ptr < len(m.code) && m.code[ptr] > 0 {
+		m.codeWrites = append(m.codeWrites, ptr)
+	}
+	if ptr >= m.Size() && ptr < m.Size()+pageSize {
+		m.grow(ptr + 1)
+	}
+	if ptr < m.Size() {
+		m.storage[ptr] = v
+		if ptr < len(m.decoded) {
+			m.decoded[ptr] = nil
+		}
+		return nil
+	}
+	if m.sparse == nil {
+		m.sparse = map[int]int{}
+	}
+	if v == 0 {
+		delete(m.sparse, ptr)
+	} else {
+		m.sparse[ptr] = v
 	}
-	m.storage[ptr] = v
 	return nil
 }
 
-func (m *Memory) opcodeFetch() (int, error) {
-	return m.read(Immediate, 0)
+// grow extends the dense storage to hold at least n words, rounded up to
+// a whole page, and moves over sparse words that now fall inside it
+func (m *Memory) grow(n int) {
+	n = (n + pageSize - 1) / pageSize * pageSize
+	if n > m.MaxSize() {
+		n = m.MaxSize()
+	}
+	if m.logger != nil {
+		m.logger.Log(LevelDebug, "[Memory] Growing", "from", m.Size(), "to", n)
+	}
+	m.storage = append(m.storage, make([]int, n-m.Size())...)
+	for a, v := range m.sparse {
+		if a < n {
+			m.storage[a] = v
+			delete(m.sparse, a)
+		}
+	}
+}
+
+// fetchInstruction fetches and decodes the instruction word at ptr, from
+// the cache when it has been decoded before
+func (m *Memory) fetchInstruction(ptr int) (int, *Instruction, error) {
+	if ptr >= 0 && ptr < len(m.decoded) {
+		if ins := m.decoded[ptr]; ins != nil {
+			return m.storage[ptr], ins, nil
+		}
+	}
+	code, err := m.readAddress(ptr)
+	if err != nil {
+		return code, nil, err
+	}
+	if m.noCache {
+		return code, decode(code), nil
+	}
+	ins := m.interned[code]
+	if ins == nil || len(ins.ParamAddrModes) != ParamCount(ins.Opcode) {
+		ins = decode(code)
+		if m.interned == nil {
+			m.interned = map[int]*Instruction{}
+		}
+		m.interned[code] = ins
+	}
+	if ptr < m.Size() && (len(m.mappings) == 0 || m.mapping(ptr) == nil) {
+		if len(m.decoded) < m.Size() {
+			m.decoded = append(m.decoded, make([]*Instruction, m.Size()-len(m.decoded))...)
+		}
+		m.decoded[ptr] = ins
+	}
+	return code, ins, nil
 }
@@ -0,0 +1,71 @@
+package intcomputer
+
+import "testing"
+
+func TestMemory_Grow(t *testing.T) {
+	c := CreateIntComputer([]int{99}, CreateLogger(), nil, nil)
+
+	tt := []struct {
+		ptr, val int
+	}{
+		{ptr: 1, val: 7},
+		{ptr: 100, val: 8},
+		{ptr: 1 << 20, val: 9},
+		{ptr: DefaultMaxMemory - 1, val: 10},
+	}
+
+	for _, tc := range tt {
+		if err := c.Store(tc.val, tc.ptr); err != nil {
+			t.Fatalf("Store(%d, %d): %s", tc.val, tc.ptr, err)
+		}
+	}
+
+	for _, tc := range tt {
+		v, err := c.ReadMemory(tc.ptr, 1)
+		if err != nil {
+			t.Fatalf("ReadMemory(%d): %s", tc.ptr, err)
+		}
+		if v[0] != tc.val {
+			t.Errorf("mem[%d]= %d expected %d", tc.ptr, v[0], tc.val)
+		}
+	}
+
+	// untouched memory reads as zero
+	v, err := c.ReadMemory(50, 3)
+	if err != nil {
+		t.Fatalf("ReadMemory: %s", err)
+	}
+	for i, x := range v {
+		if x != 0 {
+			t.Errorf("mem[%d]= %d expected 0", 50+i, x)
+		}
+	}
+
+	if c.Mem.Size() >= 1<<20 {
+		t.Errorf("far write grew dense storage to %d words", c.Mem.Size())
+	}
+}
+
+func TestMemory_MaxSize(t *testing.T) {
+	c := CreateIntComputer([]int{99}, CreateLogger(), nil, nil)
+	c.Mem.SetMaxSize(64)
+
+	if err := c.Store(1, 63); err != nil {
+		t.Errorf("Store below limit: %s", err)
+	}
+	if err := c.Store(1, 64); err == nil {
+		t.Errorf("Store past limit succeeded")
+	}
+	if _, err := c.ReadMemory(64, 1); err == nil {
+		t.Errorf("ReadMemory past limit succeeded")
+	}
+	if err := c.Store(1, -1); err == nil {
+		t.Errorf("Store at negative address succeeded")
+	}
+
+	// the limit survives loading a new program
+	c.Program([]int{99})
+	if c.Mem.MaxSize() != 64 {
+		t.Errorf("MaxSize after Program: %d expected 64", c.Mem.MaxSize())
+	}
+}
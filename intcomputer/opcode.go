@@ -0,0 +1,120 @@
+package intcomputer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Handler executes an instruction whose parameters are resolved: values
+// for parameters that are read and addresses for those that are written
+// to, which the handler stores to with Store. InPtr already points past
+// the instruction, a handler that jumps sets it. A handler returning
+// ErrNoInput from ReadInput waits for input and one calling Halt halts,
+// both leave the computer at the instruction. params is only valid during
+// the call.
+type Handler func(c *IntComputer, params []int) error
+
+// OpcodeDef defines an instruction
+type OpcodeDef struct {
+	// Code is the opcode, from 0 to 99
+	Code int
+	// Name is the mnemonic, unique ignoring case
+	Name   string
+	Params int
+	// Writes lists the parameters giving the address to store results to
+	Writes []int
+	Exec   Handler
+}
+
+// maxParams is the most parameters an instruction may take
+const maxParams = 16
+
+type opcode struct {
+	OpcodeDef
+	// bit i set when parameter i is written to
+	writes uint64
+}
+
+// the instruction set, indexed by opcode
+var opcodes [100]*opcode
+
+// RegisterOpcode adds an instruction to the instruction set of every
+// computer, the disassembler and the assembler. It is not safe to call
+// while computers are running, do it from an init function.
+func RegisterOpcode(def OpcodeDef) error {
+	if def.Code < 0 || def.Code >= len(opcodes) {
+		return fmt.Errorf("Opcode %d out of range", def.Code)
+	}
+	if o := opcodes[def.Code]; o != nil {
+		return fmt.Errorf("Opcode %d already registered as %s", def.Code, o.Name)
+	}
+	if def.Name == "" || strings.ContainsAny(def.Name, " \t,;:#@.") {
+		return fmt.Errorf("Invalid mnemonic %q", def.Name)
+	}
+	if op, ok := LookupOpcode(def.Name); ok {
+		return fmt.Errorf("Mnemonic %s already registered for opcode %d", def.Name, op)
+	}
+	if def.Params < 0 || def.Params > maxParams {
+		return fmt.Errorf("Opcode %d: invalid parameter count %d", def.Code, def.Params)
+	}
+	if def.Exec == nil {
+		return fmt.Errorf("Opcode %d: no handler", def.Code)
+	}
+	o := &opcode{OpcodeDef: def}
+	o.Writes = append([]int{}, def.Writes...)
+	for _, i := range o.Writes {
+		if i < 0 || i >= def.Params {
+			return fmt.Errorf("Opcode %d: write parameter %d out of range", def.Code, i)
+		}
+		o.writes |= 1 << uint(i)
+	}
+	opcodes[def.Code] = o
+	return nil
+}
+
+// UnregisterOpcode removes an instruction from the instruction set
+func UnregisterOpcode(code int) {
+	if code >= 0 && code < len(opcodes) {
+		opcodes[code] = nil
+	}
+}
+
+// Opcodes lists the definitions of the instruction set, by opcode
+func Opcodes() []OpcodeDef {
+	ret := []OpcodeDef{}
+	for _, o := range opcodes {
+		if o != nil {
+			def := o.OpcodeDef
+			def.Writes = append([]int{}, o.Writes...)
+			ret = append(ret, def)
+		}
+	}
+	return ret
+}
+
+func lookup(op int) *opcode {
+	if op < 0 || op >= len(opcodes) {
+		return nil
+	}
+	return opcodes[op]
+}
+
+func mustRegister(def OpcodeDef) {
+	if err := RegisterOpcode(def); err != nil {
+		panic(err)
+	}
+}
+
+func init() {
+	mustRegister(OpcodeDef{Code: Add, Name: "Add", Params: 3, Writes: []int{2}, Exec: add})
+	mustRegister(OpcodeDef{Code: Mul, Name: "Mul", Params: 3, Writes: []int{2}, Exec: mul})
+	mustRegister(OpcodeDef{Code: Input, Name: "Input", Params: 1, Writes: []int{0}, Exec: input})
+	mustRegister(OpcodeDef{Code: Output, Name: "Output", Params: 1, Exec: output})
+	mustRegister(OpcodeDef{Code: JmpIfTrue, Name: "JumpIfTrue", Params: 2, Exec: jmpIfTrue})
+	mustRegister(OpcodeDef{Code: JmpIfFalse, Name: "JumpIfFalse", Params: 2, Exec: jmpIfFalse})
+	mustRegister(OpcodeDef{Code: LessThan, Name: "LessThan", Params: 3, Writes: []int{2}, Exec: lt})
+	mustRegister(OpcodeDef{Code: Equals, Name: "Equals", Params: 3, Writes: []int{2}, Exec: eq})
+	mustRegister(OpcodeDef{Code: AdjustRelBase, Name: "AdjustRelBase", Params: 1,
+		Exec: adjustRelBase})
+	mustRegister(OpcodeDef{Code: Halt, Name: "Halt", Exec: halt})
+}
@@ -0,0 +1,90 @@
+package intcomputer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterOpcode(t *testing.T) {
+	// Max a, b, dst
+	if err := RegisterOpcode(OpcodeDef{Code: 12, Name: "Max", Params: 3,
+		Writes: []int{2}, Exec: func(c *IntComputer, params []int) error {
+			if params[0] > params[1] {
+				return c.Store(params[0], params[2])
+			}
+			return c.Store(params[1], params[2])
+		}}); err != nil {
+		t.Fatal(err)
+	}
+	// JumpIfNeg v, target
+	if err := RegisterOpcode(OpcodeDef{Code: 13, Name: "JumpIfNeg", Params: 2,
+		Exec: func(c *IntComputer, params []int) error {
+			if params[0] < 0 {
+				c.InPtr = params[1]
+			}
+			return nil
+		}}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterOpcode(12)
+	defer UnregisterOpcode(13)
+
+	// outputs max(input, 3), or -1 for negative inputs
+	program := []int{3, 17, 1013, 17, 14, 1012, 17, 3, 17, 4, 17, 99,
+		0, 0, 104, -1, 99, 0}
+	for _, tc := range []struct{ in, out int }{{1, 3}, {7, 7}, {-2, -1}} {
+		var out []int
+		c := CreateIntComputer(program, CreateLogger(), nil, func(v int) {
+			out = append(out, v)
+		})
+		c.QueueInput(tc.in)
+		res, err := c.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Reason != StopHalted || !equalInts(out, []int{tc.out}) {
+			t.Errorf("input %d: %s output %v expected %d", tc.in, res, out, tc.out)
+		}
+	}
+
+	if op, ok := LookupOpcode("max"); !ok || op != 12 || ParamCount(12) != 3 ||
+		!isWriteParam(12, 2) || isWriteParam(12, 0) {
+		t.Errorf("Max not registered: %d %v", op, ok)
+	}
+	sb := &strings.Builder{}
+	if err := WriteListing(sb, program[:12]); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []string{"0002: JumpIfNeg 17, #14", "0005: Max 17, #3, 17"} {
+		if !strings.Contains(sb.String(), l) {
+			t.Errorf("listing lacks %q:\n%s", l, sb)
+		}
+	}
+
+	UnregisterOpcode(13)
+	c := CreateIntComputer(program, CreateLogger(), nil, nil)
+	c.QueueInput(1)
+	if _, err := c.Run(); err == nil || c.InPtr != 2 {
+		t.Errorf("unregistered opcode ran, err= %v InPtr= %d", err, c.InPtr)
+	}
+}
+
+func TestRegisterOpcode_Invalid(t *testing.T) {
+	nop := func(c *IntComputer, params []int) error { return nil }
+	for _, def := range []OpcodeDef{
+		{Code: Add, Name: "Plus", Exec: nop},
+		{Code: 100, Name: "Big", Exec: nop},
+		{Code: 50, Name: "add", Exec: nop},
+		{Code: 50, Name: "Two words", Exec: nop},
+		{Code: 50, Name: "Nop"},
+		{Code: 50, Name: "Nop", Params: 1, Writes: []int{1}, Exec: nop},
+	} {
+		if err := RegisterOpcode(def); err == nil {
+			UnregisterOpcode(def.Code)
+			t.Errorf("registered %+v", def)
+		}
+	}
+	if len(Opcodes()) != 10 {
+		t.Errorf("instruction set: %+v", Opcodes())
+	}
+}
@@ -0,0 +1,202 @@
+package intcomputer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type SnapshotFormat int
+
+const (
+	SnapshotJSON SnapshotFormat = iota
+	SnapshotBinary
+)
+
+// snapshotVersion is bumped whenever the saved state changes. Version 1
+// has no program image.
+const snapshotVersion = 2
+
+// binary snapshots start with the magic followed by the version
+var snapshotMagic = []byte("ICSNAP")
+
+type snapshotFile struct {
+	Version  int       `json:"version"`
+	Snapshot *Snapshot `json:"snapshot"`
+}
+
+// Write saves s to w in format f
+func (s *Snapshot) Write(w io.Writer, f SnapshotFormat) error {
+	switch f {
+	case SnapshotJSON:
+		enc := json.NewEncoder(w)
+		return enc.Encode(&snapshotFile{Version: snapshotVersion, Snapshot: s})
+	case SnapshotBinary:
+		return s.writeBinary(w)
+	}
+	return fmt.Errorf("Unsupported snapshot format %d", f)
+}
+
+func (s *Snapshot) writeBinary(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, binary.MaxVarintLen64)
+	putInt := func(v int) {
+		bw.Write(buf[:binary.PutVarint(buf, int64(v))])
+	}
+	putInts := func(vs []int) {
+		putInt(len(vs))
+		for _, v := range vs {
+			putInt(v)
+		}
+	}
+	putBool := func(b bool) {
+		if b {
+			putInt(1)
+		} else {
+			putInt(0)
+		}
+	}
+
+	bw.Write(snapshotMagic)
+	putInt(snapshotVersion)
+	putInts(s.Image)
+	putInts(s.Memory)
+	addrs := make([]int, 0, len(s.Sparse))
+	for a := range s.Sparse {
+		addrs = append(addrs, a)
+	}
+	sort.Ints(addrs)
+	putInt(len(addrs))
+	for _, a := range addrs {
+		putInt(a)
+		putInt(s.Sparse[a])
+	}
+	putInt(s.MaxSize)
+	putInt(s.InPtr)
+	putInt(s.RelBase)
+	putInt(int(s.Flags))
+	putInt(s.Executed)
+	putInts(s.InQueue)
+	putInt(int(s.Arithmetic))
+	putBool(s.Resuming)
+	putInt(s.ResumeAt)
+	return bw.Flush()
+}
+
+// ReadSnapshot loads a snapshot saved in either format
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(len(snapshotMagic))
+	if err == nil && bytes.Equal(head, snapshotMagic) {
+		br.Discard(len(snapshotMagic))
+		return readBinarySnapshot(br)
+	}
+
+	f := &snapshotFile{}
+	if err := json.NewDecoder(br).Decode(f); err != nil {
+		return nil, fmt.Errorf("Reading snapshot: %w", err)
+	}
+	if f.Version < 1 || f.Version > snapshotVersion {
+		return nil, fmt.Errorf("Unsupported snapshot version %d", f.Version)
+	}
+	if f.Snapshot == nil {
+		return nil, errors.New("Snapshot missing")
+	}
+	return f.Snapshot, nil
+}
+
+func readBinarySnapshot(r *bufio.Reader) (*Snapshot, error) {
+	var err error
+	getInt := func() int {
+		if err != nil {
+			return 0
+		}
+		var v int64
+		v, err = binary.ReadVarint(r)
+		return int(v)
+	}
+	getLen := func() int {
+		n := getInt()
+		if err == nil && n < 0 {
+			err = fmt.Errorf("Invalid length %d", n)
+		}
+		return n
+	}
+	getInts := func() []int {
+		n := getLen()
+		if err != nil || n == 0 {
+			return nil
+		}
+		var vs []int
+		for i := 0; i < n && err == nil; i++ {
+			vs = append(vs, getInt())
+		}
+		return vs
+	}
+
+	version := getInt()
+	if err == nil && (version < 1 || version > snapshotVersion) {
+		return nil, fmt.Errorf("Unsupported snapshot version %d", version)
+	}
+	s := &Snapshot{}
+	if version >= 2 {
+		s.Image = getInts()
+	}
+	s.Memory = getInts()
+	if n := getLen(); err == nil && n > 0 {
+		s.Sparse = map[int]int{}
+		for i := 0; i < n && err == nil; i++ {
+			a := getInt()
+			s.Sparse[a] = getInt()
+		}
+	}
+	s.MaxSize = getInt()
+	s.InPtr = getInt()
+	s.RelBase = getInt()
+	s.Flags = uint16(getInt())
+	s.Executed = getInt()
+	s.InQueue = getInts()
+	s.Arithmetic = Arithmetic(getInt())
+	s.Resuming = getInt() != 0
+	s.ResumeAt = getInt()
+	if err != nil {
+		return nil, fmt.Errorf("Reading snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// SaveState writes a snapshot of the computer to the file at path
+func (c *IntComputer) SaveState(path string, f SnapshotFormat) error {
+	fh, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := c.Snapshot().Write(fh, f); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// LoadState restores the computer from a snapshot file written by
+// SaveState, in either format
+func (c *IntComputer) LoadState(path string) error {
+	fh, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	s, err := ReadSnapshot(fh)
+	if err != nil {
+		return err
+	}
+	c.Restore(s)
+	return nil
+}
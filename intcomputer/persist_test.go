@@ -0,0 +1,64 @@
+package intcomputer
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntComputer_SaveLoadState(t *testing.T) {
+
+	// add two inputs into 11 and output the sum
+	instructions := []int{3, 11, 3, 12, 1, 11, 12, 11, 4, 11, 99, 0, 0}
+	c := CreateIntComputer(instructions, CreateLogger(), nil, nil)
+	c.Store(5, 1<<20)
+	c.RelBase = -3
+	c.QueueInput(10)
+	c.Run()
+	c.QueueInput(20, 30)
+	snap := c.Snapshot()
+
+	for _, f := range []SnapshotFormat{SnapshotJSON, SnapshotBinary} {
+		path := filepath.Join(t.TempDir(), "state")
+		if err := c.SaveState(path, f); err != nil {
+			t.Fatalf("format %d: SaveState: %s", f, err)
+		}
+
+		out := []int{}
+		loaded := CreateIntComputer([]int{99}, CreateLogger(), nil, func(n int) {
+			out = append(out, n)
+		})
+		if err := loaded.LoadState(path); err != nil {
+			t.Fatalf("format %d: LoadState: %s", f, err)
+		}
+		if !reflect.DeepEqual(loaded.Snapshot(), snap) {
+			t.Fatalf("format %d: loaded %+v expected %+v", f, loaded.Snapshot(), snap)
+		}
+
+		if _, err := loaded.Resume(); err != nil {
+			t.Fatalf("format %d: %s", f, err)
+		}
+		if !equalInts(out, []int{30}) || !loaded.IsHalted() {
+			t.Errorf("format %d: output %v expected [30]", f, out)
+		}
+	}
+}
+
+func TestReadSnapshotVersion(t *testing.T) {
+	if _, err := ReadSnapshot(strings.NewReader(`{"version": 99, "snapshot": {}}`)); err == nil {
+		t.Errorf("read a JSON snapshot of an unknown version")
+	}
+
+	b := &bytes.Buffer{}
+	(&Snapshot{Memory: []int{99}}).Write(b, SnapshotBinary)
+	data := b.Bytes()
+	if _, err := ReadSnapshot(bytes.NewReader(data[:len(data)-2])); err == nil {
+		t.Errorf("read a truncated binary snapshot")
+	}
+	data[len(snapshotMagic)] = 42
+	if _, err := ReadSnapshot(bytes.NewReader(data)); err == nil {
+		t.Errorf("read a binary snapshot of an unknown version")
+	}
+}
@@ -0,0 +1,193 @@
+package intcomputer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BranchCount counts which way a conditional jump went
+type BranchCount struct {
+	Taken    int `json:"taken"`
+	NotTaken int `json:"not_taken"`
+}
+
+// Profile is a Tracer counting what a program does: instructions per
+// opcode and per address, conditional jumps taken and memory accesses per
+// address. Set it as the Tracer of a computer to profile it, or combine
+// it with other tracers with MultiTracer.
+type Profile struct {
+	mu       sync.Mutex
+	Steps    int                  `json:"steps"`
+	Opcodes  map[int]int          `json:"opcodes"`
+	Addrs    map[int]int          `json:"addrs"`
+	Branches map[int]*BranchCount `json:"branches"`
+	Reads    map[int]int          `json:"reads"`
+	Writes   map[int]int          `json:"writes"`
+	// codes holds the last instruction word executed at each address, for
+	// code that is not in the program image
+	codes map[int]int
+}
+
+func NewProfile() *Profile {
+	return &Profile{
+		Opcodes:  map[int]int{},
+		Addrs:    map[int]int{},
+		Branches: map[int]*BranchCount{},
+		Reads:    map[int]int{},
+		Writes:   map[int]int{},
+		codes:    map[int]int{},
+	}
+}
+
+func (p *Profile) Trace(s *StepInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.Steps++
+	op := s.Instruction.Opcode
+	p.Opcodes[op]++
+	p.Addrs[s.InPtr]++
+	p.codes[s.InPtr] = s.Code
+	countBranch(p.Branches, s)
+	for _, r := range s.Reads {
+		p.Reads[r.Addr]++
+	}
+	for _, w := range s.Writes {
+		p.Writes[w.Addr]++
+	}
+}
+
+// countBranch counts the direction of s into m if it is a conditional
+// jump
+func countBranch(m map[int]*BranchCount, s *StepInfo) {
+	var taken bool
+	switch s.Instruction.Opcode {
+	case JmpIfTrue:
+		taken = s.Operands[0] != 0
+	case JmpIfFalse:
+		taken = s.Operands[0] == 0
+	default:
+		return
+	}
+	b := m[s.InPtr]
+	if b == nil {
+		b = &BranchCount{}
+		m[s.InPtr] = b
+	}
+	if taken {
+		b.Taken++
+	} else {
+		b.NotTaken++
+	}
+}
+
+// Count is a count for an opcode or an address
+type Count struct {
+	Key, N int
+}
+
+// sortedCounts orders m by count, highest first, and keeps the first n (n
+// < 0 keeps all)
+func sortedCounts(m map[int]int, n int) []Count {
+	ret := make([]Count, 0, len(m))
+	for k, v := range m {
+		ret = append(ret, Count{Key: k, N: v})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].N != ret[j].N {
+			return ret[i].N > ret[j].N
+		}
+		return ret[i].Key < ret[j].Key
+	})
+	if n >= 0 && n < len(ret) {
+		ret = ret[:n]
+	}
+	return ret
+}
+
+// Hottest returns the n most executed instruction addresses
+func (p *Profile) Hottest(n int) []Count {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return sortedCounts(p.Addrs, n)
+}
+
+// WriteJSON writes the profile as JSON
+func (p *Profile) WriteJSON(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// WriteReport writes a text report of the profile, listing the top most
+// executed addresses with their disassembly in program and the top most
+// accessed memory addresses
+func (p *Profile) WriteReport(w io.Writer, program []int, top int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lines := map[int]*Line{}
+	for _, l := range Disassemble(program) {
+		lines[l.Addr] = l
+	}
+	disasm := func(addr int) string {
+		if l, ok := lines[addr]; ok && l.Instruction != nil && l.Words[0] == p.codes[addr] {
+			return l.String()
+		}
+		// code reached other than by the linear sweep
+		if l := DisassembleAt(program, addr); l != nil && l.Words[0] == p.codes[addr] {
+			return l.String()
+		}
+		// or written by the program
+		return fmt.Sprintf("%04d: %-30s ; modified, code %d", addr,
+			OpcodeName(decode(p.codes[addr]).Opcode), p.codes[addr])
+	}
+	percent := func(n int) float64 {
+		if p.Steps == 0 {
+			return 0
+		}
+		return 100 * float64(n) / float64(p.Steps)
+	}
+
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "%d instructions executed\n\nopcodes:\n", p.Steps)
+	for _, c := range sortedCounts(p.Opcodes, -1) {
+		fmt.Fprintf(sb, "  %-12s %10d %6.2f%%\n", OpcodeName(c.Key), c.N, percent(c.N))
+	}
+
+	fmt.Fprintf(sb, "\nhottest addresses:\n")
+	for _, c := range sortedCounts(p.Addrs, top) {
+		fmt.Fprintf(sb, "  %10d %6.2f%%  %s\n", c.N, percent(c.N), disasm(c.Key))
+	}
+
+	if len(p.Branches) > 0 {
+		fmt.Fprintf(sb, "\nbranches:            taken  not taken\n")
+		addrs := make([]int, 0, len(p.Branches))
+		for a := range p.Branches {
+			addrs = append(addrs, a)
+		}
+		sort.Ints(addrs)
+		for _, a := range addrs {
+			b := p.Branches[a]
+			fmt.Fprintf(sb, "  %04d %18d %10d\n", a, b.Taken, b.NotTaken)
+		}
+	}
+
+	for _, m := range []struct {
+		name   string
+		counts map[int]int
+	}{{"reads", p.Reads}, {"writes", p.Writes}} {
+		fmt.Fprintf(sb, "\nmemory %s:\n", m.name)
+		for _, c := range sortedCounts(m.counts, top) {
+			fmt.Fprintf(sb, "  [%d] %d\n", c.Key, c.N)
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
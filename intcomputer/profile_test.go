@@ -0,0 +1,61 @@
+package intcomputer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	c := CreateIntComputer(countdown, CreateLogger(), nil, func(n int) {})
+	p := NewProfile()
+	ring := NewRingTracer(1)
+	c.Tracer = MultiTracer{p, ring}
+	c.QueueInput(3)
+	if _, err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Steps != 11 || len(ring.Events()) != 1 {
+		t.Fatalf("profiled %d steps, ring kept %d", p.Steps, len(ring.Events()))
+	}
+	opcodes := map[int]int{Input: 1, Output: 3, Add: 3, JmpIfTrue: 3, Halt: 1}
+	for op, n := range opcodes {
+		if p.Opcodes[op] != n {
+			t.Errorf("%s executed %d times expected %d", OpcodeName(op), p.Opcodes[op], n)
+		}
+	}
+	if hot := p.Hottest(1); len(hot) != 1 || hot[0] != (Count{Key: 2, N: 3}) {
+		t.Errorf("hottest: %v", hot)
+	}
+	if b := p.Branches[8]; b == nil || *b != (BranchCount{Taken: 2, NotTaken: 1}) {
+		t.Errorf("branch at 8: %v", b)
+	}
+	if p.Reads[12] != 9 || p.Writes[12] != 4 {
+		t.Errorf("accesses to 12: %d reads, %d writes", p.Reads[12], p.Writes[12])
+	}
+
+	sb := &strings.Builder{}
+	if err := p.WriteReport(sb, countdown, 3); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"11 instructions executed", "0002: Output 12",
+		"JumpIfTrue 12, #2", "[12] 9"} {
+		if !strings.Contains(sb.String(), s) {
+			t.Errorf("report lacks %q:\n%s", s, sb)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := p.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	q := NewProfile()
+	if err := json.Unmarshal(buf.Bytes(), q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Steps != p.Steps || q.Addrs[8] != 3 || q.Branches[8].Taken != 2 {
+		t.Errorf("decoded profile: %+v", q)
+	}
+}
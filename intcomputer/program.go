@@ -0,0 +1,35 @@
+package intcomputer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ParseProgram reads a comma separated Intcode program, which may span
+// several lines
+func ParseProgram(r io.Reader) ([]int, error) {
+	ret := []int{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<24)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		for _, w := range strings.Split(line, ",") {
+			w = strings.TrimSpace(w)
+			if w == "" {
+				continue
+			}
+			x, err := strconv.Atoi(w)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid program word %q: %w", w, err)
+			}
+			ret = append(ret, x)
+		}
+	}
+	return ret, sc.Err()
+}
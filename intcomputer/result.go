@@ -0,0 +1,177 @@
+package intcomputer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StopReason tells why a run returned
+type StopReason int
+
+const (
+	StopHalted StopReason = iota
+	StopBreakpoint
+	StopAwaitingInput
+	StopStepBudget
+	StopCancelled
+	StopFault
+	StopLimitExceeded
+)
+
+func (r StopReason) String() string {
+	switch r {
+	case StopHalted:
+		return "Halted"
+	case StopBreakpoint:
+		return "Breakpoint"
+	case StopAwaitingInput:
+		return "AwaitingInput"
+	case StopStepBudget:
+		return "StepBudgetExhausted"
+	case StopCancelled:
+		return "Cancelled"
+	case StopFault:
+		return "Fault"
+	case StopLimitExceeded:
+		return "LimitExceeded"
+	}
+	return fmt.Sprintf("StopReason(%d)", int(r))
+}
+
+type RunResult struct {
+	Reason StopReason
+	// InPtr is the next instruction to execute, or the faulting one
+	InPtr int
+	// Steps is the number of instructions executed by the run, Executed
+	// the number executed since the program was loaded
+	Steps, Executed int
+	// Outputs is the number of values output by the run
+	Outputs int
+	// Breakpoint is the breakpoint or watchpoint that stopped the run,
+	// nil when stopped by Break
+	Breakpoint *Breakpoint
+	// Err is the error the run returned, if any
+	Err error
+}
+
+func (r RunResult) String() string {
+	if r.Err != nil {
+		return fmt.Sprintf("{%s InsPtr: %d Steps: %d Executed: %d Err: %s}",
+			r.Reason, r.InPtr, r.Steps, r.Executed, r.Err)
+	}
+	return fmt.Sprintf("{%s InsPtr: %d Steps: %d Executed: %d}",
+		r.Reason, r.InPtr, r.Steps, r.Executed)
+}
+
+// run executes instructions until the computer stops, budget instructions
+// have been executed (budget < 0 is unlimited), a limit is exceeded or
+// ctx, if not nil, is cancelled. ctx may carry the time limit on top of
+// parent, the context the caller passed in.
+func (c *IntComputer) run(ctx, parent context.Context, budget int) (RunResult, error) {
+	var done <-chan struct{}
+	if ctx != nil {
+		done = ctx.Done()
+	}
+	start := time.Now()
+	timedOut := func() bool {
+		return c.Limits.Timeout > 0 && time.Since(start) >= c.Limits.Timeout
+	}
+	// ctx ended through the time limit rather than by the caller
+	expired := func(err error) bool {
+		return errors.Is(err, context.DeadlineExceeded) && parent.Err() == nil
+	}
+
+	steps, nextTimeCheck := 0, 0
+	c.runOutputs = 0
+	var bp *Breakpoint
+	stop := func(reason StopReason, err error) (RunResult, error) {
+		res := RunResult{
+			Reason:     reason,
+			InPtr:      c.InPtr,
+			Steps:      steps,
+			Executed:   c.executed,
+			Outputs:    c.runOutputs,
+			Breakpoint: bp,
+			Err:        err,
+		}
+		if c.logger != nil {
+			level := LevelDebug
+			if reason == StopFault {
+				level = LevelError
+			}
+			c.logger.Log(level, "[IntComputer] Stopped", "result", res)
+		}
+		return res, err
+	}
+
+	for {
+		switch {
+		case c.IsHalted():
+			return stop(StopHalted, nil)
+		case c.isBreak():
+			return stop(StopBreakpoint, nil)
+		case c.IsAwaitingInput():
+			return stop(StopAwaitingInput, nil)
+		case budget >= 0 && steps >= budget:
+			return stop(StopStepBudget, nil)
+		case c.Limits.MaxSteps > 0 && steps >= c.Limits.MaxSteps:
+			return stop(StopLimitExceeded, &ErrLimitExceeded{Limit: LimitSteps})
+		case steps >= nextTimeCheck && timedOut():
+			return stop(StopLimitExceeded, &ErrLimitExceeded{Limit: LimitTime})
+		}
+		if steps >= nextTimeCheck {
+			nextTimeCheck = steps + timeCheckInterval
+		}
+
+		select {
+		case <-done:
+			if expired(ctx.Err()) {
+				return stop(StopLimitExceeded, &ErrLimitExceeded{Limit: LimitTime})
+			}
+			return stop(StopCancelled, ctx.Err())
+		default:
+		}
+
+		if len(c.breakpoints) > 0 && !(c.resuming && c.resumeAt == c.InPtr) {
+			if bp = c.checkBreakpoints(); bp != nil {
+				c.resuming, c.resumeAt = true, c.InPtr
+				return stop(StopBreakpoint, nil)
+			}
+		}
+
+		n, err := 0, error(nil)
+		if c.useCompiled() {
+			max := nextTimeCheck - steps
+			if budget >= 0 && budget-steps < max {
+				max = budget - steps
+			}
+			if c.Limits.MaxSteps > 0 && c.Limits.MaxSteps-steps < max {
+				max = c.Limits.MaxSteps - steps
+			}
+			n, err = c.runCompiled(max)
+		} else {
+			var rec *StepInfo
+			if c.Tracer != nil {
+				rec = &StepInfo{InPtr: c.InPtr}
+			}
+			if err = c.executeStep(rec); err == nil && !c.IsAwaitingInput() {
+				n = 1
+			}
+		}
+		steps += n
+		if err != nil {
+			var le *ErrLimitExceeded
+			switch {
+			case errors.As(err, &le):
+				return stop(StopLimitExceeded, err)
+			case done != nil && err == ctx.Err() && expired(err):
+				return stop(StopLimitExceeded, &ErrLimitExceeded{Limit: LimitTime})
+			case done != nil && err == ctx.Err():
+				return stop(StopCancelled, err)
+			}
+			return stop(StopFault, err)
+		}
+	}
+}
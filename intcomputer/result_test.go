@@ -0,0 +1,49 @@
+package intcomputer
+
+import "testing"
+
+func TestIntComputer_RunResult(t *testing.T) {
+
+	// output an input twice and halt
+	instructions := []int{3, 9, 4, 9, 4, 9, 99, 0, 0, 0}
+	var c *IntComputer
+	c = CreateIntComputer(instructions, CreateLogger(), nil, func(n int) {
+		c.Break()
+	})
+
+	expect := func(res RunResult, err error, reason StopReason,
+		inPtr, steps, executed int) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if res.Reason != reason || res.InPtr != inPtr || res.Steps != steps ||
+			res.Executed != executed {
+			t.Fatalf("result: %s expected {%s InsPtr: %d Steps: %d Executed: %d}",
+				res, reason, inPtr, steps, executed)
+		}
+	}
+
+	res, err := c.Run()
+	expect(res, err, StopAwaitingInput, 0, 0, 0)
+
+	c.QueueInput(5)
+	res, err = c.Resume()
+	expect(res, err, StopBreakpoint, 4, 2, 2)
+
+	res, err = c.Resume()
+	expect(res, err, StopBreakpoint, 6, 1, 3)
+
+	c.flags &= 0xfffd
+	res, err = c.RunFor(0)
+	expect(res, err, StopStepBudget, 6, 0, 3)
+
+	res, err = c.RunFor(1)
+	expect(res, err, StopHalted, 6, 1, 4)
+
+	c.Program([]int{42})
+	res, err = c.Run()
+	if err == nil || res.Reason != StopFault || res.Err != err {
+		t.Fatalf("result: %s expected a fault", res)
+	}
+}
@@ -0,0 +1,97 @@
+package intcomputer
+
+// Snapshot is a deep copy of a computer's state, sharing nothing with it
+type Snapshot struct {
+	// Image is the program Reset restores
+	Image      []int       `json:"image,omitempty"`
+	Memory     []int       `json:"memory"`
+	Sparse     map[int]int `json:"sparse,omitempty"`
+	MaxSize    int         `json:"maxSize"`
+	InPtr      int         `json:"inPtr"`
+	RelBase    int         `json:"relBase"`
+	Flags      uint16      `json:"flags"`
+	Executed   int         `json:"executed"`
+	InQueue    []int       `json:"inQueue,omitempty"`
+	Arithmetic Arithmetic  `json:"arithmetic"`
+	// set when stopped at a breakpoint that is skipped on resuming
+	Resuming bool `json:"resuming,omitempty"`
+	ResumeAt int  `json:"resumeAt,omitempty"`
+}
+
+func copyInts(s []int) []int {
+	if s == nil {
+		return nil
+	}
+	ret := make([]int, len(s))
+	copy(ret, s)
+	return ret
+}
+
+func copyMap(m map[int]int) map[int]int {
+	if m == nil {
+		return nil
+	}
+	ret := make(map[int]int, len(m))
+	for k, v := range m {
+		ret[k] = v
+	}
+	return ret
+}
+
+func (c *IntComputer) Snapshot() *Snapshot {
+	return &Snapshot{
+		Image:      copyInts(c.image),
+		Memory:     copyInts(c.Mem.storage),
+		Sparse:     copyMap(c.Mem.sparse),
+		MaxSize:    c.Mem.maxSize,
+		InPtr:      c.InPtr,
+		RelBase:    c.RelBase,
+		Flags:      c.flags,
+		Executed:   c.executed,
+		InQueue:    copyInts(c.inQueue),
+		Arithmetic: c.Arithmetic,
+		Resuming:   c.resuming,
+		ResumeAt:   c.resumeAt,
+	}
+}
+
+// Restore puts the computer back in the state of s, which it does not
+// share any memory with afterwards. Breakpoints and I/O methods are kept,
+// so is the program image if s has none.
+func (c *IntComputer) Restore(s *Snapshot) {
+	if s.Image != nil {
+		c.image = copyInts(s.Image)
+	}
+	c.Mem.load(copyInts(s.Memory))
+	c.Mem.sparse = copyMap(s.Sparse)
+	c.Mem.maxSize = s.MaxSize
+	c.InPtr = s.InPtr
+	c.RelBase = s.RelBase
+	c.flags = s.Flags
+	c.executed = s.Executed
+	c.inQueue = copyInts(s.InQueue)
+	c.Arithmetic = s.Arithmetic
+	c.resuming, c.resumeAt = s.Resuming, s.ResumeAt
+}
+
+// Clone forks the computer. The clone shares the logger, I/O methods,
+// tracer and mapped devices but none of the state, and has the same
+// limits and engine and copies of the breakpoints.
+func (c *IntComputer) Clone() *IntComputer {
+	ret := CreateIntComputer(nil, c.logger, c.InFunc, c.OutFunc)
+	ret.Restore(c.Snapshot())
+	ret.Engine = c.Engine
+	ret.Limits = c.Limits
+	ret.Tracer = c.Tracer
+	ret.Mem.mappings = c.Mem.Mappings()
+	for _, b := range c.breakpoints {
+		bp := *b
+		if b.Watch != nil {
+			w := *b.Watch
+			bp.Watch = &w
+		}
+		ret.breakpoints = append(ret.breakpoints, &bp)
+	}
+	ret.lastBreakpointID = c.lastBreakpointID
+	return ret
+}
@@ -0,0 +1,68 @@
+package intcomputer
+
+import "testing"
+
+func TestIntComputer_Clone(t *testing.T) {
+
+	// add two inputs into 11 and output the sum
+	instructions := []int{3, 11, 3, 12, 1, 11, 12, 11, 4, 11, 99, 0, 0}
+	var out []int
+	c := CreateIntComputer(instructions, CreateLogger(), nil, func(n int) {
+		out = append(out, n)
+	})
+	c.QueueInput(10)
+	if res, _ := c.Run(); res.Reason != StopAwaitingInput {
+		t.Fatalf("result: %s", res)
+	}
+
+	c.Limits = Limits{MaxSteps: 100}
+	c.Tracer = NewCoverage()
+	fork := c.Clone()
+	snap := c.Snapshot()
+	if fork.Limits != c.Limits || fork.Tracer != c.Tracer {
+		t.Errorf("clone limits %+v tracer %v", fork.Limits, fork.Tracer)
+	}
+
+	c.QueueInput(1)
+	fork.QueueInput(2)
+	if _, err := c.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fork.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if !equalInts(out, []int{11, 12}) {
+		t.Fatalf("output: %v expected [11 12]", out)
+	}
+	if v, _ := c.ReadMemory(11, 1); v[0] != 11 {
+		t.Errorf("computer memory changed by its clone: %d", v[0])
+	}
+
+	// back to the fork point, with nothing shared with the snapshot
+	c.Restore(snap)
+	if c.IsHalted() || !c.IsAwaitingInput() || c.InPtr != 2 {
+		t.Fatalf("restored to %d", c.InPtr)
+	}
+	c.QueueInput(3)
+	if _, err := c.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if out[len(out)-1] != 13 {
+		t.Errorf("output: %v expected 13 last", out)
+	}
+	if snap.Memory[11] != 10 || snap.InPtr != 2 {
+		t.Errorf("snapshot changed by the computer")
+	}
+}
+
+func TestIntComputer_ProgramCopies(t *testing.T) {
+	instructions := []int{1101, 1, 2, 5, 99, 0}
+	c := CreateIntComputer(nil, CreateLogger(), nil, nil)
+	c.Program(instructions)
+	if _, err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if instructions[5] != 0 {
+		t.Errorf("Program shares the caller's slice")
+	}
+}
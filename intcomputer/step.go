@@ -0,0 +1,90 @@
+package intcomputer
+
+import (
+	"fmt"
+	"strings"
+)
+
+type MemAccess struct {
+	Addr  int `json:"addr"`
+	Value int `json:"value"`
+}
+
+// StepInfo describes what a single instruction did
+type StepInfo struct {
+	InPtr       int
+	Code        int
+	Instruction *Instruction
+	// Operands are the resolved parameters, values for parameters that are
+	// read and addresses for those that are written to
+	Operands []int
+	// Reads are the memory words read by Position and Relative parameters
+	Reads  []MemAccess
+	Writes []MemAccess
+	Input  []int
+	Output []int
+	// AwaitingInput is set when the instruction was not executed for lack
+	// of input
+	AwaitingInput bool
+}
+
+func (s *StepInfo) String() string {
+	if s.Instruction == nil {
+		return fmt.Sprintf("{InsPtr: %d}", s.InPtr)
+	}
+	sb := &strings.Builder{}
+	sb.WriteString(fmt.Sprintf("{InsPtr: %d Code: %d} %s %v",
+		s.InPtr, s.Code, OpcodeName(s.Instruction.Opcode), s.Operands))
+	for _, w := range s.Writes {
+		sb.WriteString(fmt.Sprintf(" [%d]<-%d", w.Addr, w.Value))
+	}
+	if len(s.Input) > 0 {
+		sb.WriteString(fmt.Sprintf(" in=%v", s.Input))
+	}
+	if len(s.Output) > 0 {
+		sb.WriteString(fmt.Sprintf(" out=%v", s.Output))
+	}
+	if s.AwaitingInput {
+		sb.WriteString(" awaiting input")
+	}
+	return sb.String()
+}
+
+// Step executes exactly one instruction, regardless of breaks, and
+// returns what it did. It returns nil when the computer is halted. A step
+// that is starved for input leaves the computer at the Input instruction.
+func (c *IntComputer) Step() (*StepInfo, error) {
+	if c.IsHalted() {
+		return nil, nil
+	}
+	c.flags &= 0xfff9
+
+	rec := &StepInfo{InPtr: c.InPtr}
+	c.runOutputs = 0
+	err := c.executeStep(rec)
+	return rec, err
+}
+
+// executeStep executes the next instruction, recording what it did into
+// rec when it is not nil and handing it to the Tracer
+func (c *IntComputer) executeStep(rec *StepInfo) error {
+	c.rec = rec
+	err := c.execute()
+	c.rec = nil
+	if err != nil {
+		return err
+	}
+	if c.IsAwaitingInput() {
+		if rec != nil {
+			rec.AwaitingInput = true
+		}
+		return nil
+	}
+
+	c.executed++
+	c.resuming = false
+	if rec != nil && c.Tracer != nil {
+		c.Tracer.Trace(rec)
+	}
+	return nil
+}
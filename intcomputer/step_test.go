@@ -0,0 +1,84 @@
+package intcomputer
+
+import "testing"
+
+func TestIntComputer_Step(t *testing.T) {
+
+	// read an input, compare it with 8 and output the result
+	instructions := []int{3, 9, 8, 9, 10, 9, 4, 9, 99, -1, 8}
+	out := []int{}
+	c := CreateIntComputer(instructions, CreateLogger(), nil, func(n int) {
+		out = append(out, n)
+	})
+
+	s, err := c.Step()
+	if err != nil || !s.AwaitingInput || c.InPtr != 0 {
+		t.Fatalf("starved step: %v %v", s, err)
+	}
+
+	c.QueueInput(8)
+	expected := []struct {
+		inPtr, opcode int
+		operands      []int
+		reads         []MemAccess
+		writes        []MemAccess
+		input, output []int
+	}{
+		{inPtr: 0, opcode: Input, operands: []int{9},
+			writes: []MemAccess{{Addr: 9, Value: 8}}, input: []int{8}},
+		{inPtr: 2, opcode: Equals, operands: []int{8, 8, 9},
+			reads:  []MemAccess{{Addr: 9, Value: 8}, {Addr: 10, Value: 8}},
+			writes: []MemAccess{{Addr: 9, Value: 1}}},
+		{inPtr: 6, opcode: Output, operands: []int{1},
+			reads: []MemAccess{{Addr: 9, Value: 1}}, output: []int{1}},
+		{inPtr: 8, opcode: Halt},
+	}
+
+	for i, e := range expected {
+		s, err := c.Step()
+		if err != nil {
+			t.Fatalf("step %d: %s", i, err)
+		}
+		t.Logf("step %d: %s", i, s)
+		if s.InPtr != e.inPtr || s.Instruction.Opcode != e.opcode ||
+			!equalInts(s.Operands, e.operands) || !equalInts(s.Input, e.input) ||
+			!equalInts(s.Output, e.output) || !equalAccesses(s.Reads, e.reads) ||
+			!equalAccesses(s.Writes, e.writes) {
+			t.Errorf("step %d: %s expected %+v", i, s, e)
+		}
+	}
+
+	if s, err := c.Step(); s != nil || err != nil || !c.IsHalted() {
+		t.Errorf("step after halt: %v %v", s, err)
+	}
+	if !equalInts(out, []int{1}) {
+		t.Errorf("output: %v expected [1]", out)
+	}
+	if res, _ := c.Run(); res.Executed != 4 {
+		t.Errorf("executed: %d expected 4", res.Executed)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalAccesses(a, b []MemAccess) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
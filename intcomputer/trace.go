@@ -0,0 +1,108 @@
+package intcomputer
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Tracer receives a record of every instruction a computer executes. A
+// computer without a Tracer does not record anything.
+type Tracer interface {
+	Trace(s *StepInfo)
+}
+
+// RingTracer keeps the most recent instructions, up to a fixed number
+type RingTracer struct {
+	mu     sync.Mutex
+	events []*StepInfo
+	next   int
+	full   bool
+}
+
+func NewRingTracer(n int) *RingTracer {
+	if n < 1 {
+		n = 1
+	}
+	return &RingTracer{events: make([]*StepInfo, n)}
+}
+
+func (r *RingTracer) Trace(s *StepInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[r.next] = s
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Events returns the kept instructions, oldest first
+func (r *RingTracer) Events() []*StepInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]*StepInfo{}, r.events[:r.next]...)
+	}
+	return append(append([]*StepInfo{}, r.events[r.next:]...), r.events[:r.next]...)
+}
+
+type traceRecord struct {
+	InPtr    int         `json:"ip"`
+	Code     int         `json:"code"`
+	Opcode   int         `json:"opcode"`
+	Op       string      `json:"op"`
+	Modes    []int       `json:"modes"`
+	Operands []int       `json:"operands"`
+	Reads    []MemAccess `json:"reads,omitempty"`
+	Writes   []MemAccess `json:"writes,omitempty"`
+	Input    []int       `json:"input,omitempty"`
+	Output   []int       `json:"output,omitempty"`
+}
+
+// JSONTracer writes every instruction as a line of JSON. Writing stops at
+// the first error, which Err reports.
+type JSONTracer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+func NewJSONTracer(w io.Writer) *JSONTracer {
+	return &JSONTracer{enc: json.NewEncoder(w)}
+}
+
+func (j *JSONTracer) Trace(s *StepInfo) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.err != nil {
+		return
+	}
+	j.err = j.enc.Encode(&traceRecord{
+		InPtr:    s.InPtr,
+		Code:     s.Code,
+		Opcode:   s.Instruction.Opcode,
+		Op:       OpcodeName(s.Instruction.Opcode),
+		Modes:    s.Instruction.ParamAddrModes,
+		Operands: s.Operands,
+		Reads:    s.Reads,
+		Writes:   s.Writes,
+		Input:    s.Input,
+		Output:   s.Output,
+	})
+}
+
+func (j *JSONTracer) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// MultiTracer hands every instruction to each of its tracers in turn
+type MultiTracer []Tracer
+
+func (m MultiTracer) Trace(s *StepInfo) {
+	for _, t := range m {
+		t.Trace(s)
+	}
+}
@@ -0,0 +1,90 @@
+package intcomputer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// counts down from the input to 0, outputting every value
+var countdown = []int{3, 12, 4, 12, 1001, 12, -1, 12, 1005, 12, 2, 99, 0}
+
+func TestRingTracer(t *testing.T) {
+	c := CreateIntComputer(countdown, CreateLogger(), nil, func(n int) {})
+	tr := NewRingTracer(4)
+	c.Tracer = tr
+	c.QueueInput(3)
+
+	res, err := c.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := tr.Events()
+	if len(events) != 4 {
+		t.Fatalf("kept %d events expected 4", len(events))
+	}
+	// the last loop iteration and the halt
+	expected := []int{2, 4, 8, 11}
+	for i, e := range events {
+		if e.InPtr != expected[i] {
+			t.Errorf("event %d: %s expected InsPtr %d", i, e, expected[i])
+		}
+	}
+	if last := events[3]; last.Instruction.Opcode != Halt {
+		t.Errorf("last event %s expected Halt", last)
+	}
+	if res.Executed != 1+3*3+1 {
+		t.Errorf("executed: %d", res.Executed)
+	}
+
+	short := NewRingTracer(100)
+	c.Tracer = short
+	c.Reset()
+	c.QueueInput(1)
+	c.Run()
+	if n := len(short.Events()); n != 5 {
+		t.Errorf("kept %d events expected 5", n)
+	}
+}
+
+func TestJSONTracer(t *testing.T) {
+	c := CreateIntComputer(countdown, CreateLogger(), nil, func(n int) {})
+	b := &bytes.Buffer{}
+	tr := NewJSONTracer(b)
+	c.Tracer = tr
+	c.QueueInput(1)
+	if _, err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if tr.Err() != nil {
+		t.Fatal(tr.Err())
+	}
+
+	records := []traceRecord{}
+	sc := bufio.NewScanner(b)
+	for sc.Scan() {
+		r := traceRecord{}
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("line %q: %s", sc.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 5 {
+		t.Fatalf("%d records expected 5", len(records))
+	}
+
+	in, out, dec := records[0], records[1], records[2]
+	if in.Op != "Input" || !equalInts(in.Input, []int{1}) ||
+		!equalAccesses(in.Writes, []MemAccess{{Addr: 12, Value: 1}}) {
+		t.Errorf("input record %+v", in)
+	}
+	if out.Op != "Output" || !equalInts(out.Output, []int{1}) {
+		t.Errorf("output record %+v", out)
+	}
+	if dec.InPtr != 4 || dec.Code != 1001 || !equalInts(dec.Modes, []int{0, 1, 0}) ||
+		!equalInts(dec.Operands, []int{1, -1, 12}) {
+		t.Errorf("add record %+v", dec)
+	}
+}
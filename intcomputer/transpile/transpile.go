@@ -0,0 +1,434 @@
+// Package transpile translates Intcode programs to Go.
+//
+// The generated function runs the program natively, one case of a switch
+// per basic block found by package cfg, with memory, the relative base and
+// I/O through the InputMethod and OutputMethod signatures:
+//
+//	func Run(in func() int, out func(int)) error
+//
+// Code the translation can not follow is handed over to the intcomputer
+// interpreter, together with the memory at that point: jumps to addresses
+// that are not the start of a known block, words that are not an
+// instruction, writes to translated instruction words and anything that
+// would fault. Parameter words the program writes to itself are read from
+// memory rather than translated to constants.
+package transpile
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer/cfg"
+)
+
+// Options control the generated code
+type Options struct {
+	// Package is the package of the generated file, main by default. A
+	// main package also gets a main function reading inputs from stdin and
+	// printing outputs one per line.
+	Package string
+	// Func is the name of the generated function, Run by default
+	Func string
+	// Entries are addresses to translate code from besides 0, such as
+	// the targets of indirect jumps seen when running the program
+	Entries []int
+}
+
+type generator struct {
+	sb *strings.Builder
+	// operand words the program writes to, read from memory at run time
+	dynamic map[int]bool
+	// words translated to Go
+	code map[int]bool
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.sb, format, args...)
+}
+
+// Targets is a Tracer recording where taken jumps went, code only reached
+// by indirect jumps is found by running the program with it and passing
+// Entries on to Generate
+type Targets map[int]bool
+
+func (t Targets) Trace(s *intcomputer.StepInfo) {
+	if s.Instruction == nil || len(s.Operands) < 2 {
+		return
+	}
+	switch s.Instruction.Opcode {
+	case intcomputer.JmpIfTrue:
+		if s.Operands[0] != 0 {
+			t[s.Operands[1]] = true
+		}
+	case intcomputer.JmpIfFalse:
+		if s.Operands[0] == 0 {
+			t[s.Operands[1]] = true
+		}
+	}
+}
+
+// Entries returns the recorded targets in order
+func (t Targets) Entries() []int {
+	ret := make([]int, 0, len(t))
+	for a := range t {
+		ret = append(ret, a)
+	}
+	sort.Ints(ret)
+	return ret
+}
+
+// handover ends the translation, continuing at pc with the interpreter
+func (g *generator) handover(pc string) {
+	g.printf("return m.interpret(%s, rb, in, out)\n", pc)
+}
+
+// word is the expression for the parameter word at addr
+func (g *generator) word(addr, v int) string {
+	if g.dynamic[addr] {
+		return fmt.Sprintf("m.load(%d)", addr)
+	}
+	return fmt.Sprintf("%d", v)
+}
+
+// operand returns the expression for parameter i of l, its value or for
+// parameters written to its address, and the address expression that
+// needs to be checked against the memory bounds first if any
+func (g *generator) operand(l *intcomputer.Line, i int) (expr, check string) {
+	o := l.Operands[i]
+	w := g.word(l.Addr+1+i, o.Value)
+	addr := w
+	if o.Mode == intcomputer.Relative {
+		addr = fmt.Sprintf("rb + %s", w)
+	}
+	constant := o.Mode == intcomputer.Position && !g.dynamic[l.Addr+1+i]
+	if constant && o.Value >= 0 && o.Value < intcomputer.DefaultMaxMemory {
+		check = ""
+	} else {
+		check = addr
+	}
+
+	switch {
+	case o.Write:
+		return addr, check
+	case o.Mode == intcomputer.Immediate:
+		return w, ""
+	}
+	return fmt.Sprintf("m.load(%s)", addr), check
+}
+
+// instruction translates l, the code jumps to the next block itself when
+// it ends one
+func (g *generator) instruction(l *intcomputer.Line) {
+	g.printf("// %s\n", strings.TrimSpace(strings.SplitN(l.String(), ";", 2)[0]))
+	n := len(l.Operands)
+	ops, checks := make([]string, n), []string{}
+	for i := range l.Operands {
+		var check string
+		ops[i], check = g.operand(l, i)
+		if check != "" {
+			checks = append(checks, fmt.Sprintf("!valid(%s)", check))
+		}
+	}
+	if len(checks) > 0 {
+		g.printf("if %s {\n", strings.Join(checks, " || "))
+		g.handover(fmt.Sprintf("%d", l.Addr))
+		g.printf("}\n")
+	}
+
+	next := l.Addr + l.Len()
+	store := func(addr, v string) {
+		o := l.Operands[len(l.Operands)-1]
+		if o.Mode == intcomputer.Position && !g.dynamic[l.Addr+len(l.Operands)] && !g.code[o.Value] {
+			g.printf("m.store(%s, %s)\n", addr, v)
+			return
+		}
+		g.printf("if m.store(%s, %s) {\n", addr, v)
+		g.handover(fmt.Sprintf("%d", next))
+		g.printf("}\n")
+	}
+	switch l.Instruction.Opcode {
+	case intcomputer.Add:
+		store(ops[2], fmt.Sprintf("%s + %s", ops[0], ops[1]))
+	case intcomputer.Mul:
+		store(ops[2], fmt.Sprintf("%s * %s", ops[0], ops[1]))
+	case intcomputer.LessThan:
+		store(ops[2], fmt.Sprintf("b2i(%s < %s)", ops[0], ops[1]))
+	case intcomputer.Equals:
+		store(ops[2], fmt.Sprintf("b2i(%s == %s)", ops[0], ops[1]))
+	case intcomputer.Input:
+		g.printf("if in == nil {\n")
+		g.handover(fmt.Sprintf("%d", l.Addr))
+		g.printf("}\n")
+		store(ops[0], "in()")
+	case intcomputer.Output:
+		g.printf("out(%s)\n", ops[0])
+	case intcomputer.JmpIfTrue, intcomputer.JmpIfFalse:
+		cmp := "!="
+		if l.Instruction.Opcode == intcomputer.JmpIfFalse {
+			cmp = "=="
+		}
+		cond := l.Operands[0]
+		if cond.Mode == intcomputer.Immediate && !g.dynamic[l.Addr+1] {
+			jumps := cond.Value != 0
+			if l.Instruction.Opcode == intcomputer.JmpIfFalse {
+				jumps = !jumps
+			}
+			if jumps {
+				g.printf("pc = %s\ncontinue\n", ops[1])
+			} else {
+				g.printf("pc = %d\ncontinue\n", next)
+			}
+			return
+		}
+		g.printf("if %s %s 0 {\npc = %s\ncontinue\n}\n", ops[0], cmp, ops[1])
+		g.printf("pc = %d\ncontinue\n", next)
+	case intcomputer.AdjustRelBase:
+		g.printf("rb += %s\n", ops[0])
+	case intcomputer.Halt:
+		g.printf("return nil\n")
+	default:
+		// registered at run time, unknown here
+		g.handover(fmt.Sprintf("%d", l.Addr))
+	}
+}
+
+func (g *generator) block(b *cfg.Block) {
+	g.printf("case %d:\n", b.Start)
+	for _, l := range b.Lines {
+		g.instruction(l)
+		switch l.Instruction.Opcode {
+		case intcomputer.Add, intcomputer.Mul, intcomputer.LessThan, intcomputer.Equals,
+			intcomputer.Input, intcomputer.Output, intcomputer.AdjustRelBase:
+		default:
+			// jumped, halted or handed over
+			return
+		}
+	}
+	if b.Invalid {
+		g.printf("// %04d: not an instruction\n", b.End-1)
+		g.handover(fmt.Sprintf("%d", b.End-1))
+		return
+	}
+	g.printf("pc = %d\ncontinue\n", b.End)
+}
+
+// Generate writes the Go translation of program to w
+func Generate(w io.Writer, program []int, opts Options) error {
+	if opts.Package == "" {
+		opts.Package = "main"
+	}
+	if opts.Func == "" {
+		opts.Func = "Run"
+	}
+
+	graph := cfg.Analyze(program, opts.Entries...)
+	g := &generator{sb: &strings.Builder{}, dynamic: map[int]bool{}, code: map[int]bool{}}
+
+	// words of translated instructions, less the parameter words written
+	// to which are read from memory
+	opcodes := map[int]bool{}
+	for _, b := range graph.Blocks {
+		for _, l := range b.Lines {
+			opcodes[l.Addr] = true
+		}
+	}
+	for _, cw := range graph.CodeWrites {
+		if !opcodes[cw.Addr] {
+			g.dynamic[cw.Addr] = true
+		}
+	}
+	codeWords := []int{}
+	for _, b := range graph.Blocks {
+		for _, l := range b.Lines {
+			for a := l.Addr; a < l.Addr+l.Len(); a++ {
+				if !g.dynamic[a] && !g.code[a] {
+					g.code[a] = true
+					codeWords = append(codeWords, a)
+				}
+			}
+		}
+	}
+	sort.Ints(codeWords)
+
+	g.printf("// Code generated by intcode transpile. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", opts.Package)
+	g.printf("import (\n")
+	if opts.Package == "main" {
+		g.printf("\"bufio\"\n\"os\"\n\"strconv\"\n\"strings\"\n")
+	}
+	g.printf("\"fmt\"\n\n\"github.com/som.subhojit1988/aoc_2k19/intcomputer\"\n)\n\n")
+
+	g.printf("var image = %s\n\n", intList(program))
+	g.printf("// words translated to Go, writing to them hands over to the interpreter\n")
+	g.printf("var codeWords = %s\n\n", intList(codeWords))
+	g.sb.WriteString(runtime)
+
+	g.printf("\n// %s runs the program, reading input with in and writing output with out\n",
+		opts.Func)
+	g.printf("func %s(in func() int, out func(int)) error {\n", opts.Func)
+	g.printf("m := newMemory()\npc, rb := 0, 0\nfor {\nswitch pc {\n")
+	for _, b := range graph.Blocks {
+		g.block(b)
+	}
+	g.printf("default:\n")
+	g.handover("pc")
+	g.printf("}\n}\n}\n")
+
+	if opts.Package == "main" {
+		g.sb.WriteString(strings.ReplaceAll(mainFunc, "Run(", opts.Func+"("))
+	}
+
+	src, err := format.Source([]byte(g.sb.String()))
+	if err != nil {
+		return fmt.Errorf("formatting generated code: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func intList(vs []int) string {
+	sb := &strings.Builder{}
+	sb.WriteString("[]int{")
+	for i, v := range vs {
+		if i%16 == 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(sb, "%d, ", v)
+	}
+	sb.WriteString("\n}")
+	return sb.String()
+}
+
+// runtime is the memory of the generated code, laid out like the
+// interpreter's so it can take over
+const runtime = `
+// writes up to a page past words grow it, like the interpreter's memory
+const pageSize = 4096
+
+type memory struct {
+	words  []int
+	sparse map[int]int
+	code   []bool
+}
+
+func newMemory() *memory {
+	m := &memory{words: append([]int{}, image...), code: make([]bool, len(image))}
+	for _, a := range codeWords {
+		m.code[a] = true
+	}
+	return m
+}
+
+func valid(a int) bool {
+	return a >= 0 && a < intcomputer.DefaultMaxMemory
+}
+
+func (m *memory) load(a int) int {
+	if a < len(m.words) {
+		return m.words[a]
+	}
+	return m.sparse[a]
+}
+
+// store writes v to a and reports whether it overwrote translated code
+func (m *memory) store(a, v int) bool {
+	if a >= len(m.words) && a < len(m.words)+pageSize {
+		m.grow(a + 1)
+	}
+	if a < len(m.words) {
+		m.words[a] = v
+		return a < len(m.code) && m.code[a]
+	}
+	if m.sparse == nil {
+		m.sparse = map[int]int{}
+	}
+	if v == 0 {
+		delete(m.sparse, a)
+	} else {
+		m.sparse[a] = v
+	}
+	return false
+}
+
+// grow extends words to hold at least n, rounded up to a whole page, and
+// moves over sparse words that now fall inside it
+func (m *memory) grow(n int) {
+	n = (n + pageSize - 1) / pageSize * pageSize
+	if n > intcomputer.DefaultMaxMemory {
+		n = intcomputer.DefaultMaxMemory
+	}
+	m.words = append(m.words, make([]int, n-len(m.words))...)
+	if len(m.sparse) == 0 {
+		return
+	}
+	for a, v := range m.sparse {
+		if a < n {
+			m.words[a] = v
+			delete(m.sparse, a)
+		}
+	}
+}
+
+// interpret runs the rest of the program from pc with the interpreter
+func (m *memory) interpret(pc, rb int, in func() int, out func(int)) error {
+	c := intcomputer.CreateIntComputer(nil, nil, in, out)
+	c.Restore(&intcomputer.Snapshot{Memory: m.words, Sparse: m.sparse, InPtr: pc, RelBase: rb})
+	res, err := c.Run()
+	if err != nil {
+		return err
+	}
+	if res.Reason != intcomputer.StopHalted {
+		return fmt.Errorf("program stopped: %s", res)
+	}
+	return nil
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+`
+
+// mainFunc runs the program on the inputs found on stdin
+const mainFunc = `
+func main() {
+	r := bufio.NewReader(os.Stdin)
+	var ins []int
+	for {
+		l, err := r.ReadString('\n')
+		for _, w := range strings.FieldsFunc(l, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		}) {
+			v, err := strconv.Atoi(w)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			ins = append(ins, v)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	in := func() int {
+		if len(ins) == 0 {
+			fmt.Fprintln(os.Stderr, "out of input")
+			os.Exit(1)
+		}
+		v := ins[0]
+		ins = ins[1:]
+		return v
+	}
+	if err := Run(in, func(v int) { fmt.Println(v) }); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+`
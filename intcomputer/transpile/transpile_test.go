@@ -0,0 +1,191 @@
+package transpile
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/som.subhojit1988/aoc_2k19/intcomputer"
+)
+
+var (
+	countdown = []int{3, 20, 4, 20, 1001, 20, -1, 20, 1005, 20, 2, 99}
+	quine     = []int{109, 1, 204, -1, 1001, 100, 1, 100, 1008, 100, 16, 101, 1006, 101, 0, 99}
+	// turns the Add at 4 into a Mul, outputs 12
+	selfModifying = []int{1101, 1100, 2, 4, 1101, 3, 4, 13, 4, 13, 99, 0, 0, 0}
+	// reads from far away memory and writes to it relative, outputs 7
+	farAway = []int{109, 100000, 21101, 3, 4, 5, 204, 5, 99}
+	// writes 7 to the 5000 words from 100 on, outputs the last one twice
+	fill = []int{
+		109, 100, // 0: AdjustRelBase #100
+		21101, 7, 0, 0, // 2: Add #7, #0, @0
+		109, 1, // 6: AdjustRelBase #1
+		1001, 30, -1, 30, // 8: Add 30, #-1, 30
+		1005, 30, 2, // 12: JumpIfTrue 30, #2
+		204, -1, // 15: Output @-1
+		4, 5099, // 17: Output 5099
+		99, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		5000, 0,
+	}
+)
+
+func readProgram(t *testing.T, path string) []int {
+	r, err := os.Open(path)
+	if err != nil {
+		t.Skipf("%s: %s", path, err)
+	}
+	defer r.Close()
+	p, err := intcomputer.ParseProgram(r)
+	if err != nil {
+		t.Fatalf("%s: %s", path, err)
+	}
+	return p
+}
+
+// record runs program on in with the interpreter, noting jump targets
+func record(t *testing.T, program, in []int, targets Targets) []int {
+	t.Helper()
+	out := []int{}
+	c := intcomputer.CreateIntComputer(program, nil, nil, func(v int) {
+		out = append(out, v)
+	})
+	c.Tracer = targets
+	c.QueueInput(in...)
+	if res, err := c.Run(); err != nil || res.Reason != intcomputer.StopHalted {
+		t.Fatalf("interpreter: %s %v", res, err)
+	}
+	return out
+}
+
+func TestGenerate(t *testing.T) {
+	sb := &strings.Builder{}
+	if err := Generate(sb, selfModifying, Options{Package: "amp", Func: "Amplify"}); err != nil {
+		t.Fatal(err)
+	}
+	src := sb.String()
+	for _, want := range []string{
+		"package amp",
+		"func Amplify(in func() int, out func(int)) error",
+		// the write to the Add at 4 hands over after it
+		"if m.store(4, 1100+2) {\n\t\t\t\treturn m.interpret(4, rb, in, out)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("missing %q in\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, "func main()") {
+		t.Errorf("main function outside package main")
+	}
+
+	// the jump table of day 7 is only found running it
+	program := readProgram(t, "../../day7/day7-part1-input.txt")
+	targets := Targets{}
+	record(t, program, []int{3, 0}, targets)
+	sb.Reset()
+	if err := Generate(sb, program, Options{Entries: targets.Entries()}); err != nil {
+		t.Fatal(err)
+	}
+	if e := targets.Entries(); len(e) != 1 || !strings.Contains(sb.String(), fmt.Sprintf("case %d:", e[0])) {
+		t.Errorf("targets %v not translated", e)
+	}
+}
+
+// TestGenerate_Run builds the generated programs and checks that they
+// produce what the interpreter does
+func TestGenerate_Run(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	mod, err := filepath.Abs("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day5 := readProgram(t, "../../day5/day5-part2-input.txt")
+	day7 := readProgram(t, "../../day7/day7-part1-input.txt")
+	tt := []struct {
+		name    string
+		program []int
+		runs    [][]int
+		// translate the code reached by indirect jumps
+		entries bool
+	}{
+		{"countdown", countdown, [][]int{{1}, {3}, {20}}, true},
+		{"quine", quine, [][]int{nil}, true},
+		{"selfmodifying", selfModifying, [][]int{nil}, true},
+		{"faraway", farAway, [][]int{nil}, true},
+		{"fill", fill, [][]int{nil}, true},
+		{"day5", day5, [][]int{{1}, {5}}, true},
+		{"day7", day7, [][]int{{0, 0}, {1, 5}, {2, 17}, {3, 30}, {4, 1}}, true},
+		{"day7interpreted", day7, [][]int{{0, 0}, {4, 1}}, false},
+	}
+
+	dir := t.TempDir()
+	gomod := fmt.Sprintf("module gen\n\ngo 1.21\n\n"+
+		"require github.com/som.subhojit1988/aoc_2k19/intcomputer v0.0.0\n\n"+
+		"replace github.com/som.subhojit1988/aoc_2k19/intcomputer => %s\n", mod)
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][][]int{}
+	for _, tc := range tt {
+		targets := Targets{}
+		for _, in := range tc.runs {
+			want[tc.name] = append(want[tc.name], record(t, tc.program, in, targets))
+		}
+		opts := Options{}
+		if tc.entries {
+			opts.Entries = targets.Entries()
+		}
+		buf := &bytes.Buffer{}
+		if err := Generate(buf, tc.program, opts); err != nil {
+			t.Fatalf("%s: %s", tc.name, err)
+		}
+		if err := os.Mkdir(filepath.Join(dir, tc.name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, tc.name, "main.go"), buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	build := exec.Command(gobin, "build", "-o", "bin"+string(filepath.Separator), "./...")
+	build.Dir = dir
+	build.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("go build: %s\n%s", err, out)
+	}
+
+	for _, tc := range tt {
+		for i, in := range tc.runs {
+			words := make([]string, len(in))
+			for j, v := range in {
+				words[j] = fmt.Sprint(v)
+			}
+			run := exec.Command(filepath.Join(dir, "bin", tc.name))
+			run.Stdin = strings.NewReader(strings.Join(words, ",") + "\n")
+			out, err := run.Output()
+			if err != nil {
+				t.Errorf("%s %v: %s", tc.name, in, err)
+				continue
+			}
+			got := []int{}
+			for _, f := range strings.Fields(string(out)) {
+				var v int
+				fmt.Sscan(f, &v)
+				got = append(got, v)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want[tc.name][i]) {
+				t.Errorf("%s %v: got %v want %v", tc.name, in, got, want[tc.name][i])
+			}
+		}
+	}
+}
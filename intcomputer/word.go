@@ -0,0 +1,49 @@
+package intcomputer
+
+import (
+	"fmt"
+	"math"
+)
+
+// Memory words, inputs and outputs are ints, 64 bits wide on 64-bit
+// platforms. Where they are narrower CheckedArithmetic reports results
+// that do not fit instead of silently wrapping.
+
+// Arithmetic selects how Add and Mul treat results that overflow a word
+type Arithmetic int
+
+const (
+	// WrapArithmetic silently wraps around on overflow, like Go's int
+	WrapArithmetic Arithmetic = iota
+	// CheckedArithmetic fails Add and Mul with an *ErrOverflow instead
+	CheckedArithmetic
+)
+
+// ErrOverflow is the error of an Add or Mul whose result does not fit in a
+// word under CheckedArithmetic
+type ErrOverflow struct {
+	Fault
+	Op   string
+	X, Y int
+}
+
+func (e *ErrOverflow) Error() string {
+	return fmt.Sprintf("Arithmetic overflow: %d %s %d %s", e.X, e.Op, e.Y, &e.Fault)
+}
+
+func (a Arithmetic) add(x, y int) (int, error) {
+	r := x + y
+	if a == CheckedArithmetic && (x >= 0) == (y >= 0) && (r >= 0) != (x >= 0) {
+		return r, &ErrOverflow{Op: "+", X: x, Y: y}
+	}
+	return r, nil
+}
+
+func (a Arithmetic) mul(x, y int) (int, error) {
+	r := x * y
+	if a == CheckedArithmetic && x != 0 &&
+		(r/x != y || (x == -1 && y == math.MinInt)) {
+		return r, &ErrOverflow{Op: "*", X: x, Y: y}
+	}
+	return r, nil
+}
@@ -0,0 +1,59 @@
+package intcomputer
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestArithmetic(t *testing.T) {
+	tt := []struct {
+		op       string
+		x, y     int
+		overflow bool
+	}{
+		{op: "+", x: 1, y: 2},
+		{op: "+", x: math.MaxInt, y: 1, overflow: true},
+		{op: "+", x: math.MinInt, y: -1, overflow: true},
+		{op: "+", x: math.MaxInt, y: math.MinInt},
+		{op: "*", x: math.MaxInt / 3, y: 3},
+		{op: "*", x: 1 << (strconv.IntSize/2 - 1), y: 1 << (strconv.IntSize/2 - 1)},
+		{op: "*", x: math.MaxInt, y: 2, overflow: true},
+		{op: "*", x: -1, y: math.MinInt, overflow: true},
+		{op: "*", x: math.MinInt, y: -1, overflow: true},
+		{op: "*", x: 0, y: math.MinInt},
+	}
+
+	for _, tc := range tt {
+		f, g := WrapArithmetic.add, CheckedArithmetic.add
+		if tc.op == "*" {
+			f, g = WrapArithmetic.mul, CheckedArithmetic.mul
+		}
+
+		if _, err := f(tc.x, tc.y); err != nil {
+			t.Errorf("%d %s %d: wrapping arithmetic failed: %s", tc.x, tc.op, tc.y, err)
+		}
+
+		_, err := g(tc.x, tc.y)
+		var oe *ErrOverflow
+		if errors.As(err, &oe) != tc.overflow {
+			t.Errorf("%d %s %d: checked arithmetic err= %v, expected overflow= %v",
+				tc.x, tc.op, tc.y, err, tc.overflow)
+		}
+	}
+}
+
+func TestIntComputer_CheckedMul(t *testing.T) {
+	instructions := []int{1102, math.MaxInt, 2, 5, 99, 0}
+	c := CreateIntComputer(instructions, CreateLogger(), nil, nil)
+	c.Arithmetic = CheckedArithmetic
+
+	var oe *ErrOverflow
+	if _, err := c.Step(); !errors.As(err, &oe) {
+		t.Errorf("mul err= %v, expected overflow", err)
+	}
+	if v, _ := c.ReadMemory(5, 1); v[0] != 0 {
+		t.Errorf("overflowing mul stored %d", v[0])
+	}
+}